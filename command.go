@@ -0,0 +1,696 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 4:50
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"throosea.com/gofar/far"
+)
+
+const farExtension = ".far"
+
+func requireFarArgument(name string, positional []string) (string, error) {
+	if len(positional) != 1 {
+		return "", &UsageError{Message: fmt.Sprintf("%s requires exactly one far file", name)}
+	}
+	return positional[0], nil
+}
+
+func runInspect(args []string) error {
+	fs := newFlagSet("inspect", "[options] far_file")
+	jsonOutput := fs.Bool("json", false, "print the inspection as json")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	farPath, err := requireFarArgument("inspect", positional)
+	if err != nil {
+		return err
+	}
+
+	inspection, err := far.Inspect(farPath)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		dat, _ := json.MarshalIndent(inspection, "", "  ")
+		fmt.Printf("%s\n", dat)
+		return nil
+	}
+	printInspection(os.Stdout, inspection)
+	return nil
+}
+
+func printInspection(w io.Writer, inspection *far.Inspection) {
+	deployment := inspection.Deployment
+	fmt.Fprintf(w, "far : %s\n", inspection.Path)
+	fmt.Fprintf(w, "size : %d\n", inspection.Size)
+	fmt.Fprintf(w, "sha256 : %s\n", inspection.SHA256)
+	fmt.Fprintf(w, "schema version : %d\n", deployment.SchemaVersion)
+	fmt.Fprintf(w, "process : %s\n", deployment.Process)
+	fmt.Fprintf(w, "process type : %s\n", deployment.ProcessType)
+	fmt.Fprintf(w, "platform : %s\n", deployment.Platform)
+	if len(deployment.Version) > 0 {
+		fmt.Fprintf(w, "version : %s\n", deployment.Version)
+	}
+	if len(deployment.GoVersion) > 0 {
+		fmt.Fprintf(w, "go version : %s\n", deployment.GoVersion)
+	}
+	fmt.Fprintf(w, "build time : %s\n", deployment.Build.Time)
+	fmt.Fprintf(w, "build user : %s\n", deployment.Build.User)
+	if git := deployment.Build.Git; git != nil {
+		fmt.Fprintf(w, "git branch : %s\n", git.Branch)
+		fmt.Fprintf(w, "git commit : %s\n", git.Commit)
+		fmt.Fprintf(w, "git message : %s\n", strings.TrimSpace(git.Message))
+		switch {
+		case git.Dirty == nil:
+			fmt.Fprintf(w, "git dirty : unknown\n")
+		case *git.Dirty:
+			fmt.Fprintf(w, "git dirty : %d modified, %d untracked\n", len(git.Modified), len(git.Untracked))
+		}
+	}
+	for _, k := range sortedFieldNames(deployment.Extra) {
+		fmt.Fprintf(w, "%s : %v\n", k, deployment.Extra[k])
+	}
+
+	fmt.Fprintf(w, "\nbinaries\n")
+	for _, v := range inspection.Binaries {
+		fmt.Fprintf(w, "  %s : %s %s cgo=%t %s\n", v.Path, v.GoVersion, v.Platform(), v.CGO, v.MainModule)
+	}
+
+	fmt.Fprintf(w, "\nfiles\n")
+	for _, v := range inspection.Files {
+		depth := strings.Count(v.Path, "/")
+		name := filepath.Base(v.Path)
+		if v.Type == "dir" {
+			name += "/"
+		}
+		fmt.Fprintf(w, "  %s %10d %s%s\n", v.Mode, v.Size, strings.Repeat("  ", depth), name)
+	}
+}
+
+func runVerify(args []string) error {
+	fs := newFlagSet("verify", "[options] far_file")
+	pubkey := fs.String("pubkey", "", "ed25519 public key file. the far must carry a valid signature and manifest")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	farPath, err := requireFarArgument("verify", positional)
+	if err != nil {
+		return err
+	}
+
+	if len(*pubkey) > 0 {
+		publicKey, err := far.ReadPublicKey(*pubkey)
+		if err != nil {
+			return err
+		}
+		if err = far.VerifySignature(farPath, publicKey); err != nil {
+			return err
+		}
+		fmt.Printf("%s : signature OK\n", farPath)
+	}
+
+	farFile, err := far.Open(farPath)
+	if err != nil {
+		return err
+	}
+	defer farFile.Close()
+
+	// reading every entry to the end makes archive/zip check its CRC-32
+	for _, file := range farFile.Files() {
+		r, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("corrupted entry %s : %s", file.Name, err.Error())
+		}
+		_, err = io.Copy(io.Discard, r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("corrupted entry %s : %s", file.Name, err.Error())
+		}
+	}
+
+	if farFile.Find(far.ManifestFilename) != nil {
+		if err = farFile.VerifyManifest(); err != nil {
+			return err
+		}
+	} else if len(*pubkey) > 0 {
+		return fmt.Errorf("%s not found in %s", far.ManifestFilename, farPath)
+	} else {
+		fmt.Printf("%s : no %s, skip digest check\n", farPath, far.ManifestFilename)
+	}
+	if expected, err := far.ReadChecksum(farPath); err == nil {
+		sum, err := far.FileSHA256(farPath)
+		if err != nil {
+			return err
+		}
+		if sum != expected {
+			return fmt.Errorf("checksum mismatch : %s, expected %s", sum, expected)
+		}
+	}
+
+	deployment, err := farFile.ReadDeployment()
+	if err != nil {
+		return err
+	}
+	process := deployment.Process
+	if len(process) == 0 {
+		return fmt.Errorf("process is not declared in %s", far.DeploymentFilename)
+	}
+	if farFile.Find(process) == nil {
+		return fmt.Errorf("process binary %s not found", process)
+	}
+
+	fmt.Printf("%s : OK (%d entries)\n", farPath, len(farFile.Files()))
+	return nil
+}
+
+func runKeygen(args []string) error {
+	fs := newFlagSet("keygen", "[options] [name]")
+	dir := fs.String("dir", ".", "directory where <name>.key and <name>.pub are written")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	name, err := optionalProcessName(positional)
+	if err != nil {
+		return err
+	}
+	if len(name) == 0 {
+		name = "gofar"
+	}
+
+	privatePath := filepath.Join(*dir, name+".key")
+	publicPath := filepath.Join(*dir, name+".pub")
+	err = far.GenerateKeyPair(privatePath, publicPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("private key : %s\npublic key : %s\n", privatePath, publicPath)
+	return nil
+}
+
+func runDiff(args []string) error {
+	fs := newFlagSet("diff", "[options] old_far new_far")
+	jsonOutput := fs.Bool("json", false, "print the difference as json")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return &UsageError{Message: "diff requires two far files"}
+	}
+
+	d, err := far.Diff(positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		dat, _ := json.MarshalIndent(d, "", "  ")
+		fmt.Printf("%s\n", dat)
+		return nil
+	}
+	printDiff(os.Stdout, d)
+	return nil
+}
+
+func printDiff(w io.Writer, d *far.FarDiff) {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", d.Old, d.New)
+	if d.Empty() {
+		fmt.Fprintf(w, "no difference\n")
+		return
+	}
+
+	if len(d.Deployment) > 0 {
+		fmt.Fprintf(w, "\n%s\n", far.DeploymentFilename)
+		for _, v := range d.Deployment {
+			fmt.Fprintf(w, "  %s : %s -> %s\n", v.Key, formatDiffValue(v.Old), formatDiffValue(v.New))
+		}
+	}
+
+	if len(d.Added)+len(d.Removed)+len(d.Changed) > 0 {
+		fmt.Fprintf(w, "\nfiles\n")
+		for _, v := range d.Added {
+			fmt.Fprintf(w, "  A %s\n", v)
+		}
+		for _, v := range d.Removed {
+			fmt.Fprintf(w, "  D %s\n", v)
+		}
+		for _, v := range d.Changed {
+			if v.OldMode != v.NewMode {
+				fmt.Fprintf(w, "  M %s (mode %s -> %s)\n", v.Path, v.OldMode, v.NewMode)
+			} else {
+				fmt.Fprintf(w, "  M %s\n", v.Path)
+			}
+		}
+		for _, v := range d.Changed {
+			if len(v.TextDiff) > 0 {
+				fmt.Fprintf(w, "\n%s", v.TextDiff)
+			}
+		}
+	}
+
+	for _, v := range d.Binaries {
+		fmt.Fprintf(w, "\nbinary %s\n", v.Path)
+		if v.OldGoVersion != v.NewGoVersion {
+			fmt.Fprintf(w, "  go : %s -> %s\n", v.OldGoVersion, v.NewGoVersion)
+		}
+		for _, m := range v.Modules {
+			fmt.Fprintf(w, "  %s : %s -> %s\n", m.Path, formatDiffValue(m.Old), formatDiffValue(m.New))
+		}
+	}
+}
+
+func sortedFieldNames(m map[string]interface{}) []string {
+	nameList := make([]string, 0, len(m))
+	for k := range m {
+		nameList = append(nameList, k)
+	}
+	sort.Strings(nameList)
+	return nameList
+}
+
+// formatDiffValue prints an absent value as (none)
+func formatDiffValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "(none)"
+	case string:
+		if len(value) == 0 {
+			return "(none)"
+		}
+		return strings.TrimSpace(value)
+	case []interface{}, map[string]interface{}:
+		dat, _ := json.Marshal(v)
+		return string(dat)
+	}
+	return fmt.Sprint(v)
+}
+
+func runExtract(args []string) error {
+	fs := newFlagSet("extract", "[options] far_file")
+	dir := fs.String("dir", "", "target directory (default: far file name without extension)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	farPath, err := requireFarArgument("extract", positional)
+	if err != nil {
+		return err
+	}
+
+	targetDir := *dir
+	if len(targetDir) == 0 {
+		targetDir = strings.TrimSuffix(filepath.Base(farPath), farExtension)
+	}
+
+	farFile, err := far.Open(farPath)
+	if err != nil {
+		return err
+	}
+	defer farFile.Close()
+
+	err = farFile.Extract(targetDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("extracted to %s\n", targetDir)
+	return nil
+}
+
+// applicationHome returns home, FATIMA_HOME when empty
+func applicationHome(home string) (string, error) {
+	if len(home) == 0 {
+		home = os.Getenv("FATIMA_HOME")
+	}
+	if len(home) == 0 {
+		return "", &UsageError{Message: "--home or FATIMA_HOME is required"}
+	}
+	return home, nil
+}
+
+func runInstall(args []string) error {
+	fs := newFlagSet("install", "[options] far_file")
+	home := fs.String("home", "", "application home (default: $FATIMA_HOME)")
+	keep := fs.Int("keep", far.DefaultKeepReleases, "number of previous releases kept, negative keeps every release")
+	pubkey := fs.String("pubkey", "", "ed25519 public key file. refuse a far without a valid signature")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	farPath, err := requireFarArgument("install", positional)
+	if err != nil {
+		return err
+	}
+	homeDir, err := applicationHome(*home)
+	if err != nil {
+		return err
+	}
+
+	if len(*pubkey) > 0 {
+		publicKey, err := far.ReadPublicKey(*pubkey)
+		if err != nil {
+			return err
+		}
+		if err = far.VerifySignature(farPath, publicKey); err != nil {
+			return err
+		}
+	}
+	if err = verifyManifest(farPath); err != nil {
+		return err
+	}
+
+	release, err := far.Install(farPath, far.InstallOptions{Home: homeDir, Keep: *keep})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("installed %s : %s\n", release.Process, release.Dir)
+	return nil
+}
+
+func verifyManifest(farPath string) error {
+	farFile, err := far.Open(farPath)
+	if err != nil {
+		return err
+	}
+	defer farFile.Close()
+	if farFile.Find(far.ManifestFilename) == nil {
+		return nil
+	}
+	return farFile.VerifyManifest()
+}
+
+func runRollback(args []string) error {
+	fs := newFlagSet("rollback", "[options] process_name")
+	home := fs.String("home", "", "application home (default: $FATIMA_HOME)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return &UsageError{Message: "rollback requires exactly one process name"}
+	}
+	homeDir, err := applicationHome(*home)
+	if err != nil {
+		return err
+	}
+
+	release, err := far.Rollback(homeDir, positional[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rollback %s : %s\n", release.Process, release.Dir)
+	return nil
+}
+
+func findFarFiles(dir string) ([]string, error) {
+	farList := make([]string, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, farExtension) {
+			farList = append(farList, path)
+		}
+		return nil
+	})
+	return farList, err
+}
+
+func optionalProcessName(positional []string) (string, error) {
+	if len(positional) > 1 {
+		return "", &UsageError{Message: "too many arguments"}
+	}
+	if len(positional) == 1 {
+		return positional[0], nil
+	}
+	return "", nil
+}
+
+func runList(args []string) error {
+	fs := newFlagSet("list", "[options] [process_name]")
+	output := fs.String("output", "", "artifact directory")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	processName, err := optionalProcessName(positional)
+	if err != nil {
+		return err
+	}
+
+	currentWd, _ := os.Getwd()
+	dir := far.ArtifactDir(currentWd, *output, processName)
+	if index, err := far.ReadIndex(dir); err == nil && len(index.Artifacts) > 0 {
+		for _, v := range index.Artifacts {
+			fmt.Printf("%10d %s %-12s %-14s %s\n", v.Size, v.BuildTime, v.Platform, v.Version, v.Path)
+		}
+		fmt.Printf("total %d far files in %s\n", len(index.Artifacts), filepath.Join(dir, far.IndexFilename))
+		return nil
+	}
+
+	farList, err := findFarFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range farList {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		fmt.Printf("%10d %s %s\n", info.Size(), info.ModTime().Format("2006-01-02 15:04:05"), path)
+	}
+	fmt.Printf("total %d far files in %s\n", len(farList), dir)
+	return nil
+}
+
+func runClean(args []string) error {
+	fs := newFlagSet("clean", "[options] [process_name]")
+	output := fs.String("output", "", "artifact directory")
+	dryRun := fs.Bool("dry-run", false, "print files to remove without removing")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	processName, err := optionalProcessName(positional)
+	if err != nil {
+		return err
+	}
+
+	currentWd, _ := os.Getwd()
+	dir := far.ArtifactDir(currentWd, *output, processName)
+	if dir == far.ArtifactRoot(currentWd, processName) {
+		return &UsageError{Message: fmt.Sprintf("process name is required to clean %s", dir)}
+	}
+
+	farList, err := findFarFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range farList {
+		if *dryRun {
+			fmt.Printf("would remove %s\n", path)
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if err = far.RemoveArtifact(dir, filepath.ToSlash(rel)); err != nil {
+			return err
+		}
+		fmt.Printf("removed %s\n", path)
+	}
+	return nil
+}
+
+func runPrune(args []string) error {
+	fs := newFlagSet("prune", "[options] [process_name]")
+	output := fs.String("output", "", "artifact directory")
+	keepLast := fs.Int("keep-last", 0, "keep the given number of newest builds of each process")
+	keepTagged := fs.Bool("keep-tagged", false, "keep every build made from a tagged commit")
+	maxAge := fs.String("max-age", "", "remove fars older than the age e.g) 72h, 30d")
+	maxTotalSize := fs.String("max-total-size", "", "remove oldest builds until a process fits the size e.g) 512M, 2G")
+	dryRun := fs.Bool("dry-run", false, "print fars to remove without removing")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	processName, err := optionalProcessName(positional)
+	if err != nil {
+		return err
+	}
+
+	currentWd, _ := os.Getwd()
+	policy, err := far.ProjectRetention(currentWd, processName)
+	if err != nil {
+		return err
+	}
+	// flags given on the command line override retention of gofar.yaml
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		var err error
+		switch f.Name {
+		case "keep-last":
+			policy.KeepLast = *keepLast
+		case "keep-tagged":
+			policy.KeepTagged = *keepTagged
+		case "max-age":
+			policy.MaxAge, err = far.ParseAge(*maxAge)
+		case "max-total-size":
+			policy.MaxTotalSize, err = far.ParseSize(*maxTotalSize)
+		}
+		if err != nil && flagErr == nil {
+			flagErr = &UsageError{Message: err.Error()}
+		}
+	})
+	if flagErr != nil {
+		return flagErr
+	}
+	if policy.IsZero() {
+		return &UsageError{Message: "retention policy is required : --keep-last, --max-age or --max-total-size"}
+	}
+
+	dir := far.ArtifactDir(currentWd, *output, processName)
+	dirList := []string{dir}
+	if dir == far.ArtifactRoot(currentWd, processName) {
+		if dirList, err = far.FindIndexDirs(dir); err != nil {
+			return err
+		}
+	}
+
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+	removed, freed := 0, int64(0)
+	for _, indexDir := range dirList {
+		result, err := far.Prune(indexDir, policy, *dryRun)
+		if result != nil {
+			for _, v := range result.Removed {
+				fmt.Printf("%s %s\n", verb, filepath.Join(indexDir, filepath.FromSlash(v.Path)))
+			}
+			removed += len(result.Removed)
+			freed += result.Freed
+		}
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s %d far files, %d bytes\n", verb, removed, freed)
+	return nil
+}
+
+func runPublish(args []string) error {
+	fs := newFlagSet("publish", "[options] far_file")
+	repository := fs.String("url", "", "repository url receiving <url>/<process>/<version>/<far> (default: $GOFAR_PUBLISH_URL or publish.url of gofar.yaml)")
+	method := fs.String("method", "", "http method uploading files : PUT or POST (default: PUT)")
+	username := fs.String("user", os.Getenv("GOFAR_PUBLISH_USER"), "basic auth user. the password is read from $GOFAR_PUBLISH_PASSWORD")
+	retries := fs.Int("retries", far.DefaultPublishRetries, "retries of a request failing with a network error or 5xx")
+	force := fs.Bool("force", false, "overwrite a published far having a different digest")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	farPath, err := requireFarArgument("publish", positional)
+	if err != nil {
+		return err
+	}
+
+	opts := far.PublishOptions{
+		URL:      *repository,
+		Method:   *method,
+		Username: *username,
+		Password: os.Getenv("GOFAR_PUBLISH_PASSWORD"),
+		Token:    os.Getenv("GOFAR_PUBLISH_TOKEN"),
+		Retries:  *retries,
+		Force:    *force,
+	}
+	if len(opts.URL) == 0 {
+		opts.URL = os.Getenv("GOFAR_PUBLISH_URL")
+	}
+	if len(opts.URL) == 0 || len(opts.Method) == 0 {
+		if err = applyProjectPublish(farPath, &opts); err != nil {
+			return err
+		}
+	}
+	if len(opts.URL) == 0 {
+		return &UsageError{Message: "repository url is required : --url, $GOFAR_PUBLISH_URL or publish.url of gofar.yaml"}
+	}
+	if err = verifyManifest(farPath); err != nil {
+		return err
+	}
+
+	result, err := far.Publish(farPath, opts)
+	if err != nil {
+		return err
+	}
+	if result.Skipped {
+		fmt.Printf("already published %s (sha256 %s)\n", result.URL, result.SHA256)
+		return nil
+	}
+	for _, v := range result.Uploaded {
+		fmt.Printf("uploaded %s\n", v)
+	}
+	return nil
+}
+
+// applyProjectPublish fills url and method missing in opts from gofar.yaml of the far process
+func applyProjectPublish(farPath string, opts *far.PublishOptions) error {
+	farFile, err := far.Open(farPath)
+	if err != nil {
+		return err
+	}
+	deployment, err := farFile.ReadDeployment()
+	farFile.Close()
+	if err != nil {
+		return err
+	}
+
+	currentWd, _ := os.Getwd()
+	config, err := far.ProjectPublish(currentWd, deployment.Process)
+	if err != nil {
+		return err
+	}
+	if len(opts.URL) == 0 {
+		opts.URL = config.URL
+	}
+	if len(opts.Method) == 0 {
+		opts.Method = config.Method
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 20. 오후 2:00
+ */
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneFlags(t *testing.T) {
+	output := t.TempDir()
+
+	// a bad value is reported even when a later flag parses
+	err := runPrune([]string{"--output", output, "--max-age", "bogus", "--max-total-size", "1G"})
+	assert.Equal(t, ExitUsage, ExitCode(err))
+	assert.Contains(t, err.Error(), "bogus")
+
+	err = runPrune([]string{"--output", output, "--max-age", "30d", "--max-total-size", "huge"})
+	assert.Equal(t, ExitUsage, ExitCode(err))
+
+	assert.Nil(t, runPrune([]string{"--output", output, "--max-age", "30d", "--max-total-size", "1G"}))
+}
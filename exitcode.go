@@ -0,0 +1,88 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 5:10
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"throosea.com/gofar/far"
+)
+
+// process exit codes
+const (
+	ExitOK         = 0
+	ExitFailure    = 1
+	ExitUsage      = 2
+	ExitContext    = 10
+	ExitCompile    = 11
+	ExitResource   = 12
+	ExitDeployment = 13
+	ExitCompress   = 14
+	ExitHook       = 15
+	ExitSignature  = 16
+	ExitPublish    = 17
+)
+
+var exitCodeList = []struct {
+	class error
+	code  int
+}{
+	{far.ErrContext, ExitContext},
+	{far.ErrCompile, ExitCompile},
+	{far.ErrResource, ExitResource},
+	{far.ErrDeployment, ExitDeployment},
+	{far.ErrCompress, ExitCompress},
+	{far.ErrHook, ExitHook},
+	{far.ErrSignature, ExitSignature},
+	{far.ErrPublish, ExitPublish},
+}
+
+// ExitCode maps err to the process exit code
+func ExitCode(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		return ExitUsage
+	}
+
+	for _, v := range exitCodeList {
+		if errors.Is(err, v.class) {
+			return v.code
+		}
+	}
+	return ExitFailure
+}
+
+// UsageError reports invalid command line arguments
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
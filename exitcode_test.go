@@ -0,0 +1,47 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 5:30
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"throosea.com/gofar/far"
+)
+
+func TestExitCode(t *testing.T) {
+	cause := &os.PathError{Op: "open", Path: "/nowhere", Err: os.ErrNotExist}
+	err := &far.PackagingError{Class: far.ErrResource, Err: fmt.Errorf("fail to copy resource : %w", cause)}
+
+	assert.Equal(t, ExitResource, ExitCode(err))
+	assert.Equal(t, ExitCompress, ExitCode(&far.PackagingError{Class: far.ErrCompress, Err: cause}))
+	assert.Equal(t, ExitHook, ExitCode(&far.PackagingError{Class: far.ErrHook, Err: cause}))
+	assert.Equal(t, ExitSignature, ExitCode(fmt.Errorf("%w : saturn.far", far.ErrSignature)))
+	assert.Equal(t, ExitPublish, ExitCode(fmt.Errorf("%w : PUT saturn.far : 502 Bad Gateway", far.ErrPublish)))
+	assert.Equal(t, ExitUsage, ExitCode(&UsageError{Message: "too many arguments"}))
+	assert.Equal(t, ExitFailure, ExitCode(cause))
+	assert.Equal(t, ExitOK, ExitCode(nil))
+}
@@ -0,0 +1,948 @@
+/*
+ * //
+ * // Licensed to the Apache Software Foundation (ASF) under one
+ * // or more contributor license agreements.  See the NOTICE file
+ * // distributed with p work for additional information
+ * // regarding copyright ownership.  The ASF licenses p file
+ * // to you under the Apache License, Version 2.0 (the
+ * // "License"); you may not use p file except in compliance
+ * // with the License.  You may obtain a copy of the License at
+ * //
+ * //   http://www.apache.org/licenses/LICENSE-2.0
+ * //
+ * // Unless required by applicable law or agreed to in writing,
+ * // software distributed under the License is distributed on an
+ * // "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * // KIND, either express or implied.  See the License for the
+ * // specific language governing permissions and limitations
+ * // under the License.
+ * //
+ * // @project fatima
+ * // @author DeockJin Chung (jin.freestyle@gmail.com)
+ * // @date 21. 8. 8. 오후 9:24
+ * //
+ */
+
+// Package far builds fatima package (far) artifacts and reads existing ones.
+// the gofar command is a thin wrapper around Builder
+package far
+
+import (
+	"crypto/ed25519"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	resourceDirname = "resources"
+	cmdDirname      = "cmd"
+	gitDirname      = ".git"
+	gitConfigfile   = "config"
+	procTypeGeneral = "GENERAL"
+	procTypeUI      = "USER_INTERACTIVE"
+)
+
+type CmdRecord struct {
+	Path string
+}
+
+func (c CmdRecord) GetBinaryname() string {
+	return filepath.Base(c.Path)
+}
+
+func (c CmdRecord) GetMainSourcePath() string {
+	return filepath.Join(c.Path, fmt.Sprintf("%s.go", c.GetBinaryname()))
+}
+
+// Options configures a Builder. non-empty values override gofar.yaml of the project
+type Options struct {
+	// ProcessName is the exposed process. gofar.yaml process is used when empty
+	ProcessName string
+	// OSArch is a comma separated list of GOOS_GOARCH pairs e.g) linux_amd64,linux_arm64.
+	// "all" selects gofar.yaml platforms
+	OSArch string
+	// CC is the C compiler used with cgo e.g) x86_64-pc-linux-gcc
+	CC string
+	// OutputDir is the directory the far is written to as is, overwriting a far of the same name.
+	// when empty every build is kept under OutputRoot/<process>/<build id>/
+	OutputDir string
+	// OutputRoot is the local artifact repository. $GOPATH/far when empty
+	OutputRoot string
+	// NameTemplate names the far with {process}, {version}, {platform}, {commit} and {date}
+	// placeholders. {process}-{version}, followed by -{platform} for several platforms, when empty
+	NameTemplate string
+	ResourceDir  string
+	// Jobs is the number of binaries compiled concurrently. number of CPUs when zero
+	Jobs int
+	// Version is recorded in deployment.json, injected into binaries and appended to the far name.
+	// git describe of the nearest tag when empty e.g) v1.4.2-3-gabc1234-dirty
+	Version string
+	// StampPackage is the import path whose Version, Commit, Branch, BuildTime and BuildUser
+	// variables are set with -ldflags -X. main when empty
+	StampPackage string
+	// PreserveResourcePaths keeps project relative paths of scanned resources
+	// instead of flattening them into the far root. nil keeps gofar.yaml
+	PreserveResourcePaths *bool
+	// SymlinkPolicy decides how symbolic links among resources are packaged. follow when empty
+	SymlinkPolicy SymlinkPolicy
+	// SignKey is the ed25519 private key file signing each far. unsigned when empty
+	SignKey string
+	// RequireClean refuses to package a project outside of git or with uncommitted changes.
+	// nil keeps gofar.yaml
+	RequireClean *bool
+	// Reproducible makes identical inputs produce an identical far. build time
+	// and zip timestamps come from SOURCE_DATE_EPOCH or the HEAD commit time and the build user
+	// from GOFAR_BUILD_USER only. nil keeps gofar.yaml
+	Reproducible *bool
+	// Verbose prints the rule deciding each resource file
+	Verbose bool
+	// WorkDir is where project discovery starts. current directory when empty
+	WorkDir string
+	// Output receives progress messages. discarded when nil
+	Output io.Writer
+}
+
+// Artifact describes a far produced by Builder.Build
+type Artifact struct {
+	Path     string
+	Platform Platform
+	Size     int64
+	// SHA256 is the digest of the far, also written to ChecksumPath(Path)
+	SHA256 string
+	// Signature is the detached signature file, empty when not signed
+	Signature  string
+	Deployment *Deployment
+}
+
+// Builder compiles binaries of a project, collects resources and packages them into a far
+type Builder struct {
+	ProjectBaseDir        string
+	ResourceDir           string
+	ProcessList           []CmdRecord
+	ExposeProcessName     string
+	Platforms             []Platform
+	BuildOS               string
+	BuildArc              string
+	BuildCGOLink          string
+	OutputDir             string
+	OutputRoot            string
+	NameTemplate          string
+	ResourceInclude       []string
+	ResourceExclude       []string
+	DeploymentExtra       map[string]interface{}
+	Jobs                  int
+	Version               string
+	StampPackage          string
+	Verbose               bool
+	PreserveResourcePaths bool
+	SymlinkPolicy         SymlinkPolicy
+	Reproducible          bool
+	RequireClean          bool
+	SigningKey            ed25519.PrivateKey
+	PreBuildHooks         []string
+	PostBuildHooks        []string
+	workingDir            string
+	procType              string
+	farPath               string
+	farSHA256             string
+	signaturePath         string
+	deployment            *Deployment
+	buildInfo             buildInfo
+	buildID               string
+	out                   io.Writer
+}
+
+func (b Builder) Print() {
+	fmt.Fprintf(b.out, "--------------------------------------------------\n")
+	defer func() {
+		fmt.Fprintf(b.out, "--------------------------------------------------\n")
+	}()
+	fmt.Fprintf(b.out, "project base dir : %s\n", b.ProjectBaseDir)
+	fmt.Fprintf(b.out, "resource dir : %s\n", b.ResourceDir)
+	fmt.Fprintf(b.out, "expose process name : %s\n", b.ExposeProcessName)
+	if len(b.ProcessList) > 0 {
+		binList := ""
+		for i, v := range b.ProcessList {
+			if i == 0 {
+				binList = v.GetBinaryname()
+			} else {
+				binList = binList + "," + v.GetBinaryname()
+			}
+		}
+		fmt.Fprintf(b.out, "binary : %s\n", binList)
+	} else {
+		fmt.Fprintf(b.out, "binary : %s\n", b.ExposeProcessName)
+	}
+	for _, platform := range b.Platforms {
+		fmt.Fprintf(b.out, "build : GOOS=%s GOARC=%s\n", platform.OS, platform.Arch)
+	}
+	if len(b.OutputDir) > 0 {
+		fmt.Fprintf(b.out, "output dir : %s\n", b.OutputDir)
+	} else {
+		fmt.Fprintf(b.out, "output root : %s\n", b.getOutputRoot())
+	}
+	if b.Reproducible {
+		fmt.Fprintf(b.out, "reproducible : true\n")
+	}
+	if b.SigningKey != nil {
+		fmt.Fprintf(b.out, "sign : ed25519\n")
+	}
+}
+
+// PrintPlan prints what Build would do without doing it
+func (b Builder) PrintPlan() {
+	if err := b.collectBuildInfo(); err != nil {
+		fmt.Fprintf(b.out, "%s\n", err.Error())
+	}
+	if len(b.Version) > 0 {
+		fmt.Fprintf(b.out, "version : %s\n", b.Version)
+	}
+	for _, platform := range b.getTargets() {
+		b.setTarget(platform)
+		b.printTargetPlan()
+	}
+}
+
+func (b Builder) printTargetPlan() {
+	fmt.Fprintf(b.out, "dry-run plan %s\n", b.getPlatform())
+	for _, script := range b.PreBuildHooks {
+		fmt.Fprintf(b.out, " - pre_build hook : (cd %s; %s)\n", b.ProjectBaseDir, script)
+	}
+	if len(b.ProcessList) == 0 {
+		fmt.Fprintf(b.out, " - use precompiled binary : %s\n", b.getPrecompiledBinaryPath())
+	}
+	for _, cmdRecord := range b.ProcessList {
+		fmt.Fprintf(b.out, " - compile %s : (cd %s; %s)\n", cmdRecord.GetBinaryname(), cmdRecord.Path,
+			b.newGoBuild(cmdRecord.Path, filepath.Join("<working dir>", cmdRecord.GetBinaryname())))
+	}
+	if len(b.ResourceDir) > 0 {
+		fmt.Fprintf(b.out, " - copy resources from %s\n", b.ResourceDir)
+	} else {
+		fmt.Fprintf(b.out, " - scan resources under %s\n", b.ProjectBaseDir)
+	}
+	b.printResourcePlan()
+	fmt.Fprintf(b.out, " - write %s, %s\n", DeploymentFilename, ManifestFilename)
+	fmt.Fprintf(b.out, " - compress to %s\n", b.GetFarPath())
+	fmt.Fprintf(b.out, " - write checksum %s\n", ChecksumPath(b.GetFarPath()))
+	if b.SigningKey != nil {
+		fmt.Fprintf(b.out, " - write signature %s\n", SignaturePath(b.GetFarPath()))
+	}
+	for _, script := range b.PostBuildHooks {
+		fmt.Fprintf(b.out, " - post_build hook : (cd %s; %s)\n", b.ProjectBaseDir, script)
+	}
+}
+
+// Build packages the project once per platform and returns the far artifacts
+func (b *Builder) Build() ([]*Artifact, error) {
+	err := b.collectBuildInfo()
+	if err != nil {
+		return nil, newPackagingError(ErrContext, err)
+	}
+	if b.RequireClean {
+		if err = checkCleanTree(b.buildInfo.Git); err != nil {
+			return nil, newPackagingError(ErrContext, err)
+		}
+	}
+	if err = b.reserveBuildID(); err != nil {
+		return nil, newPackagingError(ErrCompress, err)
+	}
+	if len(b.OutputDir) == 0 {
+		// a failed build leaves no empty directory behind
+		defer os.Remove(b.GetFarDir())
+	}
+	artifactList := make([]*Artifact, 0)
+	for _, platform := range b.getTargets() {
+		b.setTarget(platform)
+		artifact, err := b.buildTarget()
+		if err != nil {
+			if len(b.Platforms) > 1 {
+				err = fmt.Errorf("%s : %w", platform, err)
+			}
+			return artifactList, err
+		}
+		artifactList = append(artifactList, artifact)
+	}
+	return artifactList, nil
+}
+
+// getTargets returns Platforms, or a single default target when no platform is given
+func (b Builder) getTargets() []Platform {
+	if len(b.Platforms) == 0 {
+		return []Platform{{}}
+	}
+	return b.Platforms
+}
+
+func (b *Builder) setTarget(platform Platform) {
+	b.BuildOS = platform.OS
+	b.BuildArc = platform.Arch
+	b.procType = procTypeGeneral
+	b.farPath = ""
+	b.farSHA256 = ""
+	b.signaturePath = ""
+	b.deployment = nil
+}
+
+// getPlatform returns the current target, the host platform when none is given
+func (b Builder) getPlatform() Platform {
+	if len(b.BuildOS) == 0 {
+		return hostPlatform()
+	}
+	return Platform{OS: b.BuildOS, Arch: b.BuildArc}
+}
+
+func (b *Builder) buildTarget() (*Artifact, error) {
+	var err error
+	b.workingDir, err = ioutil.TempDir("", b.ExposeProcessName)
+	if err != nil {
+		return nil, newPackagingError(ErrContext, fmt.Errorf("fail to create tmp dir : %w", err))
+	}
+
+	fmt.Fprintf(b.out, "working directory : %s\n", b.workingDir)
+	defer func() {
+		os.RemoveAll(b.workingDir)
+	}()
+
+	err = b.runHooks("pre_build", b.PreBuildHooks)
+	if err != nil {
+		return nil, newPackagingError(ErrHook, err)
+	}
+
+	err = b.prepareBinary()
+	if err != nil {
+		return nil, newPackagingError(ErrCompile, err)
+	}
+
+	err = b.prepareResource()
+	if err != nil {
+		return nil, newPackagingError(ErrResource, err)
+	}
+
+	err = b.createDeployment()
+	if err != nil {
+		return nil, newPackagingError(ErrDeployment, err)
+	}
+
+	err = b.createManifest()
+	if err != nil {
+		return nil, newPackagingError(ErrDeployment, err)
+	}
+
+	err = b.compress()
+	if err != nil {
+		return nil, newPackagingError(ErrCompress, err)
+	}
+
+	err = b.updateIndex()
+	if err != nil {
+		return nil, newPackagingError(ErrCompress, err)
+	}
+
+	err = b.runHooks("post_build", b.PostBuildHooks)
+	if err != nil {
+		return nil, newPackagingError(ErrHook, err)
+	}
+
+	fmt.Fprintf(b.out, "\nSUCCESS to packaging...\nArtifact :: %s\n\n", b.farPath)
+
+	artifact := &Artifact{Path: b.farPath, Platform: b.getPlatform(), SHA256: b.farSHA256,
+		Signature: b.signaturePath, Deployment: b.deployment}
+	if stat, err := os.Stat(b.farPath); err == nil {
+		artifact.Size = stat.Size()
+	}
+	return artifact, nil
+}
+
+// getGOPath returns $GOPATH or the go tool default ($HOME/go) when unset
+func getGOPath() string {
+	return build.Default.GOPATH
+}
+
+func (b Builder) getOutputRoot() string {
+	if len(b.OutputRoot) > 0 {
+		return b.OutputRoot
+	}
+	return DefaultArtifactRoot()
+}
+
+// getIndexDir returns the process directory holding index.json
+func (b Builder) getIndexDir() string {
+	if len(b.OutputDir) > 0 {
+		return b.OutputDir
+	}
+	return filepath.Join(b.getOutputRoot(), b.ExposeProcessName)
+}
+
+// getBuildID names the directory of a build in the artifact repository e.g) 20261018-161000-abc1234
+func (b Builder) getBuildID() string {
+	if len(b.buildID) > 0 {
+		return b.buildID
+	}
+	id := b.buildInfo.Time.UTC().Format(buildDateLayout)
+	if len(b.buildInfo.Git.AbbrevHash) > 0 {
+		id += "-" + b.buildInfo.Git.AbbrevHash
+	}
+	return id
+}
+
+// GetFarDir returns the directory where the far artifact is written
+func (b Builder) GetFarDir() string {
+	if len(b.OutputDir) > 0 {
+		return b.OutputDir
+	}
+	return filepath.Join(b.getIndexDir(), b.getBuildID())
+}
+
+// GetFarPath returns the path of the far artifact for the current target
+func (b Builder) GetFarPath() string {
+	return filepath.Join(b.GetFarDir(), b.farName())
+}
+
+const (
+	farExtension    = ".far"
+	buildDateLayout = "20060102-150405"
+
+	defaultNameTemplate              = "{process}-{version}"
+	defaultMultiPlatformNameTemplate = "{process}-{version}-{platform}"
+)
+
+var (
+	nameTemplatePlaceholders = []string{"{process}", "{version}", "{platform}", "{commit}", "{date}"}
+	placeholderPattern       = regexp.MustCompile(`\{[^}]*\}`)
+	repeatedSeparatorPattern = regexp.MustCompile(`([-_.])[-_.]+`)
+)
+
+// checkNameTemplate refuses unknown placeholders and directory separators. fars of
+// several platforms would overwrite each other without {platform}
+func checkNameTemplate(template string, platformCount int) error {
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("name template %q must not contain a directory", template)
+	}
+	if len(template) > 0 && platformCount > 1 && !strings.Contains(template, "{platform}") {
+		return fmt.Errorf("name template %q requires {platform} to build %d platforms", template, platformCount)
+	}
+	for _, v := range placeholderPattern.FindAllString(template, -1) {
+		known := false
+		for _, placeholder := range nameTemplatePlaceholders {
+			known = known || v == placeholder
+		}
+		if !known {
+			return fmt.Errorf("unknown placeholder %s in name template %q", v, template)
+		}
+	}
+	return nil
+}
+
+// farName expands the name template. separators left around empty values are collapsed
+func (b Builder) farName() string {
+	template := b.NameTemplate
+	if len(template) == 0 {
+		template = defaultNameTemplate
+		if len(b.Platforms) > 1 {
+			template = defaultMultiPlatformNameTemplate
+		}
+	}
+
+	date := ""
+	if !b.buildInfo.Time.IsZero() {
+		date = b.buildInfo.Time.UTC().Format(buildDateLayout)
+	}
+	name := strings.NewReplacer(
+		"{process}", safeFileName(b.ExposeProcessName),
+		"{version}", safeFileName(b.Version),
+		"{platform}", b.getPlatform().String(),
+		"{commit}", safeFileName(b.buildInfo.Git.AbbrevHash),
+		"{date}", date,
+	).Replace(template)
+	name = repeatedSeparatorPattern.ReplaceAllString(name, "$1")
+	return strings.Trim(name, "-_.") + farExtension
+}
+
+var unsafeFileNamePattern = regexp.MustCompile(`[^A-Za-z0-9._+-]+`)
+
+// safeFileName replaces characters unsafe in a file name e.g) release/1.4 -> release_1.4
+func safeFileName(s string) string {
+	return strings.Trim(unsafeFileNamePattern.ReplaceAllString(s, "_"), "._")
+}
+
+// ArtifactDir returns the directory holding far artifacts of processName.
+// output wins over gofar.yaml output of the project found from workDir, which wins over $GOPATH/far
+func ArtifactDir(workDir, output, processName string) string {
+	if len(output) > 0 {
+		dir, _ := filepath.Abs(output)
+		return dir
+	}
+
+	if baseDir, err := determineModuleBaseDir(workDir, processName); err == nil {
+		if config, err := loadProjectConfig(baseDir); err == nil && len(config.Output) > 0 {
+			return resolvePath(baseDir, config.Output)
+		}
+	}
+
+	root := ArtifactRoot(workDir, processName)
+	if len(processName) == 0 {
+		return root
+	}
+	return filepath.Join(root, processName)
+}
+
+// ArtifactRoot returns output_root of gofar.yaml of the project found from workDir, $GOPATH/far when absent
+func ArtifactRoot(workDir, processName string) string {
+	if baseDir, err := determineModuleBaseDir(workDir, processName); err == nil {
+		if config, err := loadProjectConfig(baseDir); err == nil && len(config.OutputRoot) > 0 {
+			return resolvePath(baseDir, config.OutputRoot)
+		}
+	}
+	return DefaultArtifactRoot()
+}
+
+// overrideBool returns the option when given, otherwise the value of gofar.yaml
+func overrideBool(configValue bool, option *bool) bool {
+	if option != nil {
+		return *option
+	}
+	return configValue
+}
+
+// DefaultArtifactRoot returns $GOPATH/far
+func DefaultArtifactRoot() string {
+	return filepath.Join(getGOPath(), "far")
+}
+
+func (b *Builder) compress() error {
+	farDir := b.GetFarDir()
+	fmt.Fprintf(b.out, "\n>> compress to %s\n", farDir)
+
+	err := EnsureDirectory(farDir)
+	if err != nil {
+		return fmt.Errorf("fail to prepare far dir : %w", err)
+	}
+
+	b.farPath = b.GetFarPath()
+	if b.Reproducible {
+		err = ZipitReproducible(b.workingDir, b.farPath, b.buildInfo.Time)
+	} else {
+		err = Zipit(b.workingDir, b.farPath)
+	}
+	if err != nil {
+		return fmt.Errorf("fail to compress : %w", err)
+	}
+
+	b.farSHA256, err = writeChecksum(b.farPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b.out, "sha256 : %s\n", b.farSHA256)
+
+	if b.SigningKey != nil {
+		b.signaturePath, err = SignFar(b.farPath, b.SigningKey)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b.out, "signature : %s\n", b.signaturePath)
+	} else {
+		// a signature left by a previous build does not match the new far
+		os.Remove(SignaturePath(b.farPath))
+	}
+
+	return nil
+}
+
+const (
+	yyyyMMddHHmmss = "2006-01-02 15:04:05"
+)
+
+// createDeployment writes deployment.json describing the far
+func (b *Builder) createDeployment() error {
+	deployment := &Deployment{
+		SchemaVersion: DeploymentSchemaVersion,
+		Process:       b.ExposeProcessName,
+		ProcessType:   b.procType,
+		Platform:      b.getPlatform().String(),
+		Version:       b.Version,
+		GoVersion:     readGoVersion(filepath.Join(b.workingDir, b.ExposeProcessName)),
+		Build: DeploymentBuild{
+			Time: b.buildInfo.formatTime(),
+			User: b.buildInfo.User,
+		},
+	}
+	var err error
+	deployment.Binaries, err = b.deploymentBinaries()
+	if err != nil {
+		return err
+	}
+	if b.buildInfo.Git.Valid {
+		deployment.Build.Git = &DeploymentGit{
+			Branch:    b.buildInfo.Git.BranchName,
+			Commit:    b.buildInfo.Git.CommitHash,
+			Message:   b.buildInfo.Git.LastCommitMessage,
+			Modified:  b.buildInfo.Git.Modified,
+			Untracked: b.buildInfo.Git.Untracked,
+		}
+		if b.buildInfo.Git.StatusKnown {
+			dirty := b.buildInfo.Git.Dirty
+			deployment.Build.Git.Dirty = &dirty
+		}
+	}
+
+	for k, v := range b.DeploymentExtra {
+		if isReservedDeploymentField(k) {
+			fmt.Fprintf(b.out, "ignore deployment field %s : reserved\n", k)
+			continue
+		}
+		if deployment.Extra == nil {
+			deployment.Extra = make(map[string]interface{})
+		}
+		deployment.Extra[k] = v
+	}
+
+	b.deployment = deployment
+	dat, err := json.Marshal(deployment)
+	if err != nil {
+		return fmt.Errorf("fail to create deployment : %w", err)
+	}
+
+	depfile := filepath.Join(b.workingDir, DeploymentFilename)
+	err = os.WriteFile(depfile, dat, 0644)
+	if err != nil {
+		return fmt.Errorf("fail to write deployment.json : %w", err)
+	}
+
+	return nil
+}
+
+// deploymentBinaries describes every binary in the working dir
+func (b Builder) deploymentBinaries() ([]DeploymentBinary, error) {
+	sourceList := make([]string, 0)
+	if len(b.ProcessList) == 0 {
+		sourceList = append(sourceList, b.getPrecompiledBinaryPath())
+	}
+	for _, v := range b.ProcessList {
+		sourceList = append(sourceList, v.Path)
+	}
+
+	binaryList := make([]DeploymentBinary, 0, len(sourceList))
+	for i, source := range sourceList {
+		name := filepath.Base(source)
+		if len(b.ProcessList) == 0 {
+			name = b.ExposeProcessName
+		}
+		binary, err := b.describeBinary(name, source)
+		if err != nil {
+			return nil, err
+		}
+		if len(b.ProcessList) > 0 {
+			binary.Source = filepath.ToSlash(relativePath(b.ProjectBaseDir, b.ProcessList[i].Path))
+		}
+		binaryList = append(binaryList, binary)
+	}
+	return binaryList, nil
+}
+
+func (b Builder) describeBinary(name, source string) (DeploymentBinary, error) {
+	binary := DeploymentBinary{Name: name, Source: filepath.ToSlash(relativePath(b.ProjectBaseDir, source)), Primary: name == b.ExposeProcessName}
+	file, err := os.Open(filepath.Join(b.workingDir, name))
+	if err != nil {
+		return binary, fmt.Errorf("fail to read binary %s : %w", name, err)
+	}
+	defer file.Close()
+
+	binary.SHA256, binary.Size, err = digest(file)
+	if err != nil {
+		return binary, fmt.Errorf("fail to digest binary %s : %w", name, err)
+	}
+
+	platform := b.getPlatform()
+	binary.GOOS, binary.GOARCH = platform.OS, platform.Arch
+	if info, err := readBinaryInfo(name, file); err == nil {
+		if len(info.GOOS) > 0 {
+			binary.GOOS, binary.GOARCH = info.GOOS, info.GOARCH
+		}
+		binary.CGO = info.CGO
+	}
+	return binary, nil
+}
+
+// readGoVersion returns the go version which compiled the binary, empty when unknown
+func readGoVersion(binaryPath string) string {
+	bi, err := buildinfo.ReadFile(binaryPath)
+	if err != nil {
+		return ""
+	}
+	return bi.GoVersion
+}
+
+// relativePath returns path relative to baseDir, path itself when it is outside of baseDir
+func relativePath(baseDir, path string) string {
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// prepare binaries...
+func (b *Builder) prepareBinary() error {
+	if len(b.ProcessList) == 0 {
+		return b.preparePrecompiledBinary()
+	}
+
+	return b.prepareCmdRecordBinary()
+}
+
+func (b Builder) getPrecompiledBinaryPath() string {
+	if len(b.BuildOS) > 0 {
+		osArch := fmt.Sprintf("%s_%s", b.BuildOS, b.BuildArc)
+		return filepath.Join(getGOPath(), "bin", osArch, b.ExposeProcessName)
+	}
+	return filepath.Join(getGOPath(), "bin", b.ExposeProcessName)
+}
+
+func (b *Builder) preparePrecompiledBinary() error {
+	var err error
+	// check pre-compiled binary
+	precompiledBin := b.getPrecompiledBinaryPath()
+	err = CheckFileExist(precompiledBin)
+	if err != nil {
+		return fmt.Errorf("cannot find precompiled binary : %s", b.ExposeProcessName)
+	}
+	cmdRecord := CmdRecord{}
+	cmdRecord.Path = precompiledBin
+	fmt.Fprintf(b.out, "using precompiled binary : %s (%s)\n", precompiledBin, getFileModtime(precompiledBin))
+
+	// binary 복사
+	targetBin := filepath.Join(b.workingDir, b.ExposeProcessName)
+	err = CopyFile(precompiledBin, targetBin)
+	if err != nil {
+		return fmt.Errorf("fail to precompiled binary copy : %w\n", err)
+	}
+	os.Chmod(targetBin, 0755)
+	return nil
+}
+
+// NewBuilder discovers the project from opts.WorkDir and prepares a build.
+// gofar.yaml at the project root provides defaults which opts override
+func NewBuilder(opts Options) (*Builder, error) {
+	out := opts.Output
+	if out == nil {
+		out = io.Discard
+	}
+	procName, osArc, cgoLink := opts.ProcessName, opts.OSArch, opts.CC
+
+	var err error
+	workDir := opts.WorkDir
+	if len(workDir) == 0 {
+		workDir, _ = os.Getwd()
+	}
+	projectBaseDir, err := determineProjectBaseDir(workDir, procName)
+	if err != nil {
+		return nil, newPackagingError(ErrContext, err)
+	}
+
+	config, err := loadProjectConfig(projectBaseDir)
+	if err != nil {
+		return nil, newPackagingError(ErrContext, err)
+	}
+	if config.Loaded {
+		fmt.Fprintf(out, "using config : %s\n", filepath.Join(projectBaseDir, configFilename))
+	}
+
+	if len(procName) == 0 {
+		procName = config.Process
+	}
+	if len(procName) == 0 {
+		return nil, newPackagingError(ErrContext, fmt.Errorf("process name is not specified"))
+	}
+	if len(osArc) == 0 && len(config.Platforms) > 0 {
+		osArc = platformAll
+	}
+	if len(cgoLink) == 0 {
+		cgoLink = config.CGO.CC
+	}
+
+	ctx := &Builder{out: out, Verbose: opts.Verbose}
+	ctx.ProjectBaseDir = projectBaseDir
+	ctx.ExposeProcessName = procName
+	ctx.procType = procTypeGeneral
+	ctx.OutputDir = resolvePath(projectBaseDir, config.Output)
+	if len(opts.OutputDir) > 0 {
+		ctx.OutputDir, _ = filepath.Abs(opts.OutputDir)
+	}
+	ctx.OutputRoot = resolvePath(projectBaseDir, config.OutputRoot)
+	if len(opts.OutputRoot) > 0 {
+		ctx.OutputRoot, _ = filepath.Abs(opts.OutputRoot)
+	}
+	ctx.NameTemplate = config.NameTemplate
+	if len(opts.NameTemplate) > 0 {
+		ctx.NameTemplate = opts.NameTemplate
+	}
+	ctx.ResourceInclude = config.Resource.Include
+	ctx.ResourceExclude = config.Resource.Exclude
+	ctx.PreserveResourcePaths = overrideBool(config.Resource.PreservePaths, opts.PreserveResourcePaths)
+	symlinkPolicy := string(opts.SymlinkPolicy)
+	if len(symlinkPolicy) == 0 {
+		symlinkPolicy = config.Resource.Symlinks
+	}
+	ctx.SymlinkPolicy, err = ParseSymlinkPolicy(symlinkPolicy)
+	if err != nil {
+		return nil, newPackagingError(ErrContext, err)
+	}
+	ctx.DeploymentExtra = config.Deployment
+	ctx.Reproducible = overrideBool(config.Reproducible, opts.Reproducible)
+	ctx.RequireClean = overrideBool(config.RequireClean, opts.RequireClean)
+	if len(opts.SignKey) > 0 {
+		ctx.SigningKey, err = ReadPrivateKey(opts.SignKey)
+		if err != nil {
+			return nil, newPackagingError(ErrContext, fmt.Errorf("fail to load sign key : %w", err))
+		}
+	}
+	ctx.PreBuildHooks = config.Hooks.PreBuild
+	ctx.PostBuildHooks = config.Hooks.PostBuild
+	ctx.Jobs = config.Jobs
+	if opts.Jobs > 0 {
+		ctx.Jobs = opts.Jobs
+	}
+	ctx.Version = config.Version
+	if len(opts.Version) > 0 {
+		ctx.Version = opts.Version
+	}
+	ctx.StampPackage = config.Stamp.Package
+	if len(opts.StampPackage) > 0 {
+		ctx.StampPackage = opts.StampPackage
+	}
+	ctx.Platforms, err = parsePlatformList(osArc, config.Platforms)
+	if err != nil {
+		return nil, newPackagingError(ErrContext, err)
+	}
+	if err = checkNameTemplate(ctx.NameTemplate, len(ctx.Platforms)); err != nil {
+		return nil, newPackagingError(ErrContext, err)
+	}
+	if len(ctx.Platforms) > 0 {
+		ctx.BuildOS = ctx.Platforms[0].OS
+		ctx.BuildArc = ctx.Platforms[0].Arch
+	}
+	if len(cgoLink) > 0 {
+		ctx.BuildCGOLink = cgoLink
+	}
+
+	determineResourceDir(ctx, config.Resource.Dir)
+	if len(opts.ResourceDir) > 0 {
+		ctx.ResourceDir, _ = filepath.Abs(opts.ResourceDir)
+		if err = CheckDirExist(ctx.ResourceDir); err != nil {
+			return nil, newPackagingError(ErrContext, err)
+		}
+	}
+	if len(config.Cmd) > 0 {
+		err = selectCmdList(ctx, config.Cmd)
+	} else {
+		err = determineCmdList(ctx)
+	}
+	if err != nil {
+		return nil, newPackagingError(ErrContext, err)
+	}
+
+	return ctx, nil
+}
+
+func determineResourceDir(ctx *Builder, configDir string) {
+	resourceDir := filepath.Join(ctx.ProjectBaseDir, resourceDirname)
+	if len(configDir) > 0 {
+		resourceDir = resolvePath(ctx.ProjectBaseDir, configDir)
+	}
+
+	err := CheckDirExist(resourceDir)
+	if err != nil {
+		return
+	}
+
+	ctx.ResourceDir = resourceDir
+}
+
+// find project base dir
+func determineProjectBaseDir(baseDir, procName string) (string, error) {
+	foundBaseDir, err := determineModuleBaseDir(baseDir, procName)
+	if err == nil {
+		return foundBaseDir, nil
+	}
+
+	// legacy : project without go.mod
+	foundBaseDir, err = FindGitConfig(baseDir)
+	if err == nil {
+		return foundBaseDir, err
+	}
+
+	if err != errGitNotFound {
+		return foundBaseDir, err
+	}
+
+	// search $GOPATH/src
+	if len(getGOPath()) == 0 {
+		return "", errGopathNotFound
+	}
+	gopathSrcDir := filepath.Join(getGOPath(), "src")
+
+	guessDir, err := FindDirectory(gopathSrcDir, procName)
+	if err != nil {
+		return guessDir, err
+	}
+
+	guessBase := filepath.Dir(guessDir)
+	if filepath.Base(guessBase) != "cmd" {
+		return guessDir, nil
+	}
+
+	// if parent is "cmd", let's assume project base to parent of cmd
+	return filepath.Dir(guessBase), nil
+}
+
+// find cmd list
+func determineCmdList(ctx *Builder) error {
+	foundDir := filepath.Join(ctx.ProjectBaseDir, cmdDirname)
+	if CheckDirExist(foundDir) != nil {
+		var err error
+		foundDir, err = FindDirectory(ctx.ProjectBaseDir, cmdDirname)
+		if err != nil {
+			return err
+		}
+	}
+
+	cmdList, err := FindSubDirectories(foundDir)
+	if err != nil {
+		return err
+	}
+	ctx.ProcessList = make([]CmdRecord, 0)
+	for _, cmd := range cmdList {
+		record := CmdRecord{}
+		record.Path = cmd
+		ctx.ProcessList = append(ctx.ProcessList, record)
+	}
+
+	return nil
+}
+
+// selectCmdList keeps only the cmd entries named in gofar.yaml.
+// an entry is either a directory name under cmd/ or a path relative to the project base
+func selectCmdList(ctx *Builder, nameList []string) error {
+	selected := make([]CmdRecord, 0)
+	for _, name := range nameList {
+		path := filepath.Join(ctx.ProjectBaseDir, cmdDirname, name)
+		if strings.ContainsRune(name, '/') || filepath.IsAbs(name) {
+			path = resolvePath(ctx.ProjectBaseDir, name)
+		}
+		if err := CheckDirExist(path); err != nil {
+			return fmt.Errorf("invalid cmd %s : %w", name, err)
+		}
+		selected = append(selected, CmdRecord{Path: path})
+	}
+
+	ctx.ProcessList = selected
+	return nil
+}
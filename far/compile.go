@@ -0,0 +1,259 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 6:40
+ */
+
+package far
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// BinaryFailure is the failure of one cmd binary
+type BinaryFailure struct {
+	Binary string
+	Err    error
+	Output string
+}
+
+// CompileError aggregates every binary which failed to build
+type CompileError struct {
+	Failures []BinaryFailure
+}
+
+func (e *CompileError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("fail to build %d binaries", len(e.Failures)))
+	for _, v := range e.Failures {
+		sb.WriteString(fmt.Sprintf("\n - %s : %s", v.Binary, v.Err.Error()))
+		if len(v.Output) > 0 {
+			sb.WriteString("\n" + strings.TrimRight(v.Output, "\n"))
+		}
+	}
+	return sb.String()
+}
+
+// runGoBuild executes a go build. tests replace it to observe the compile pool
+var runGoBuild = func(build goBuild, w io.Writer) error {
+	return build.Run(w)
+}
+
+func (b *Builder) getJobs() int {
+	if b.Jobs > 0 {
+		return b.Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// prepareCmdRecordBinary builds ProcessList with at most Jobs concurrent go builds
+func (b *Builder) prepareCmdRecordBinary() error {
+	jobs := b.getJobs()
+	fmt.Fprintf(b.out, "\n>> compiling %d binaries (jobs=%d)...\n", len(b.ProcessList), jobs)
+
+	out := &syncWriter{w: b.out}
+	failures := make([]*BinaryFailure, len(b.ProcessList))
+	sem := make(chan struct{}, jobs)
+	wg := sync.WaitGroup{}
+	for i, cmdRecord := range b.ProcessList {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, cmdRecord CmdRecord) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			failures[i] = b.compileCmdRecord(cmdRecord, out)
+		}(i, cmdRecord)
+	}
+	wg.Wait()
+
+	compileErr := &CompileError{}
+	for _, v := range failures {
+		if v != nil {
+			compileErr.Failures = append(compileErr.Failures, *v)
+		}
+	}
+	if len(compileErr.Failures) > 0 {
+		return compileErr
+	}
+	return nil
+}
+
+func (b *Builder) compileCmdRecord(cmdRecord CmdRecord, out io.Writer) *BinaryFailure {
+	cmdBinName := cmdRecord.GetBinaryname()
+	prefixed := &prefixWriter{prefix: fmt.Sprintf("[%s] ", cmdBinName), w: out}
+	defer prefixed.Flush()
+
+	targetBin := filepath.Join(b.workingDir, cmdBinName)
+	build := b.newGoBuild(cmdRecord.Path, targetBin)
+	fmt.Fprintf(prefixed, "%s\n", build)
+
+	var captured bytes.Buffer
+	err := runGoBuild(build, io.MultiWriter(prefixed, &captured))
+	if err != nil {
+		return &BinaryFailure{Binary: cmdBinName, Err: fmt.Errorf("fail to execute command : %w", err), Output: captured.String()}
+	}
+	if captured.Len() > 0 {
+		return &BinaryFailure{Binary: cmdBinName, Err: fmt.Errorf("unexpected build output"), Output: captured.String()}
+	}
+	os.Chmod(targetBin, 0755)
+	fmt.Fprintf(prefixed, "done\n")
+	return nil
+}
+
+// goBuild is a go build invocation. arguments and environment are passed
+// to the go tool as is, never through a shell
+type goBuild struct {
+	Dir  string
+	Args []string
+	// Env is appended to the environment of gofar
+	Env []string
+}
+
+func (b Builder) newGoBuild(dir, targetBin string) goBuild {
+	build := goBuild{Dir: dir, Args: []string{"build", "-o", targetBin}}
+	if b.Reproducible {
+		build.Args = append(build.Args, "-trimpath")
+	}
+	if len(b.BuildCGOLink) > 0 {
+		build.Env = append(build.Env, "CC="+b.BuildCGOLink)
+	}
+	if len(b.BuildOS) > 0 {
+		build.Env = append(build.Env, "GOOS="+b.BuildOS, "GOARCH="+b.BuildArc)
+	}
+	if len(b.BuildCGOLink) > 0 {
+		build.Env = append(build.Env, "CGO_ENABLED=1")
+	}
+	if ldflags := b.ldflags(); len(ldflags) > 0 {
+		build.Args = append(build.Args, "-ldflags="+ldflags)
+	}
+	return build
+}
+
+func (g goBuild) Run(w io.Writer) error {
+	cmd := exec.Command("go", g.Args...)
+	cmd.Dir = g.Dir
+	cmd.Env = append(os.Environ(), g.Env...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return cmd.Run()
+}
+
+// String returns the invocation as a shell command line for display
+func (g goBuild) String() string {
+	partList := make([]string, 0, len(g.Env)+len(g.Args)+1)
+	for _, v := range g.Env {
+		partList = append(partList, displayQuote(v))
+	}
+	partList = append(partList, "go")
+	for _, v := range g.Args {
+		partList = append(partList, displayQuote(v))
+	}
+	return strings.Join(partList, " ")
+}
+
+// displayQuote quotes s for /bin/sh when it contains characters the shell interprets
+func displayQuote(s string) string {
+	if len(s) > 0 && !strings.ContainsAny(s, " \t\n'\"\\$`!*?[](){}<>|&;#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// hookEnv describes the current target to hook scripts
+func (b Builder) hookEnv() []string {
+	return []string{
+		"GOFAR_PROCESS=" + b.ExposeProcessName,
+		"GOFAR_PLATFORM=" + b.getPlatform().String(),
+		"GOFAR_PROJECT_DIR=" + b.ProjectBaseDir,
+		"GOFAR_WORKING_DIR=" + b.workingDir,
+		"GOFAR_FAR_PATH=" + b.GetFarPath(),
+	}
+}
+
+// runHooks runs user declared scripts with /bin/sh in the project base dir
+func (b *Builder) runHooks(stage string, scriptList []string) error {
+	if len(scriptList) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(b.out, "\n>> running %s hooks...\n", stage)
+	prefixed := &prefixWriter{prefix: fmt.Sprintf("[%s] ", stage), w: b.out}
+	defer prefixed.Flush()
+	for _, script := range scriptList {
+		fmt.Fprintf(prefixed, "%s\n", script)
+		err := ExecuteShellStream(b.ProjectBaseDir, script, b.hookEnv(), prefixed)
+		if err != nil {
+			return fmt.Errorf("%s hook %q : %w", stage, script, err)
+		}
+	}
+	return nil
+}
+
+// syncWriter serializes writes of concurrent builds
+type syncWriter struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.w.Write(p)
+}
+
+// prefixWriter writes complete lines to w, each starting with prefix
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.buf = append(p.buf, data...)
+	for {
+		idx := bytes.IndexByte(p.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		if _, err := io.WriteString(p.w, p.prefix+string(p.buf[:idx+1])); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[idx+1:]
+	}
+	return len(data), nil
+}
+
+// Flush writes a trailing line without newline
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		io.WriteString(p.w, p.prefix+string(p.buf)+"\n")
+		p.buf = nil
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 7:00
+ */
+
+package far
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{prefix: "[saturn] ", w: &out}
+	w.Write([]byte("first li"))
+	w.Write([]byte("ne\nsecond\nthird"))
+	w.Flush()
+	assert.Equal(t, "[saturn] first line\n[saturn] second\n[saturn] third\n", out.String())
+}
+
+func TestCompileErrorAggregates(t *testing.T) {
+	err := newPackagingError(ErrCompile, &CompileError{Failures: []BinaryFailure{
+		{Binary: "saturn", Err: errors.New("exit status 1"), Output: "undefined: x\n"},
+		{Binary: "jupiter", Err: errors.New("exit status 2")},
+	}})
+
+	var compileErr *CompileError
+	assert.True(t, errors.As(err, &compileErr))
+	assert.Len(t, compileErr.Failures, 2)
+	assert.Contains(t, err.Error(), "fail to build 2 binaries")
+	assert.Contains(t, err.Error(), " - jupiter : exit status 2")
+}
+
+func TestGoBuildKeepsArgumentsIntact(t *testing.T) {
+	b := Builder{BuildOS: "linux", BuildArc: "arm64", BuildCGOLink: "gcc; rm -rf /"}
+	b.buildInfo.User = "$(id)"
+
+	build := b.newGoBuild("/src/cmd/saturn", "/tmp/work dir/saturn")
+	assert.Equal(t, []string{"CC=gcc; rm -rf /", "GOOS=linux", "GOARCH=arm64", "CGO_ENABLED=1"}, build.Env)
+	assert.Equal(t, []string{"build", "-o", "/tmp/work dir/saturn", "-ldflags=" + b.ldflags()}, build.Args)
+	assert.Contains(t, build.Args[3], "main.BuildUser=$(id)")
+
+	assert.True(t, strings.HasPrefix(build.String(), "'CC=gcc; rm -rf /' GOOS=linux GOARCH=arm64 CGO_ENABLED=1 go build -o '/tmp/work dir/saturn' "))
+}
+
+func TestCompilePoolBoundsJobs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a project")
+	}
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/saturn\n\ngo 1.18\n")
+	b := &Builder{Jobs: 2, workingDir: t.TempDir(), out: io.Discard}
+	for _, name := range []string{"saturn", "titan", "rhea", "mimas", "enceladus", "tethys"} {
+		source := "package main\n\nfunc main() {}\n"
+		if name == "titan" || name == "mimas" || name == "tethys" {
+			source = "package main\n\nfunc main() {\n"
+		}
+		writeTestFile(t, filepath.Join(root, "cmd", name, "main.go"), source)
+		b.ProcessList = append(b.ProcessList, CmdRecord{Path: filepath.Join(root, "cmd", name)})
+	}
+
+	mutex := sync.Mutex{}
+	running, peak := 0, 0
+	defer func(run func(goBuild, io.Writer) error) { runGoBuild = run }(runGoBuild)
+	runGoBuild = func(build goBuild, w io.Writer) error {
+		mutex.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mutex.Unlock()
+		defer func() {
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+		}()
+		// keep the slot long enough for the other builds to pile up
+		time.Sleep(20 * time.Millisecond)
+		return build.Run(w)
+	}
+
+	err := b.prepareCmdRecordBinary()
+	var compileErr *CompileError
+	assert.True(t, errors.As(err, &compileErr))
+	failed := make([]string, 0)
+	for _, v := range compileErr.Failures {
+		failed = append(failed, v.Binary)
+		assert.NotEmpty(t, v.Output)
+	}
+	sort.Strings(failed)
+	assert.Equal(t, []string{"mimas", "tethys", "titan"}, failed)
+	assert.LessOrEqual(t, peak, b.Jobs)
+	assert.Equal(t, 2, peak)
+}
@@ -0,0 +1,173 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 3:20
+ */
+
+package far
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configFilename = "gofar.yaml"
+
+// ProjectConfig is the content of gofar.yaml placed at the project root
+//
+//	process: saturn
+//	cmd: [saturn, saturn-cli]
+//	resource:
+//	  dir: resources
+//	  include: ["*.yaml", "*.properties"]
+//	  exclude: ["testdata/*", "docker-compose.yml"]
+//	  preserve_paths: false
+//	  symlinks: follow
+//	platforms: [linux_amd64]
+//	cgo:
+//	  cc: x86_64-pc-linux-gcc
+//	output: dist
+//	output_root: /var/far
+//	name_template: "{process}-{version}-{platform}"
+//	jobs: 4
+//	version: 1.4.2
+//	reproducible: true
+//	require_clean: true
+//	stamp:
+//	  package: example.com/saturn/internal/buildinfo
+//	deployment:
+//	  team: platform
+//	hooks:
+//	  pre_build: ["./scripts/generate.sh"]
+//	  post_build: ["./scripts/notify.sh"]
+//	retention:
+//	  keep_last: 5
+//	  keep_tagged: true
+//	  max_age: 30d
+//	  max_total_size: 2G
+//	publish:
+//	  url: https://nexus.example.com/repository/far-raw
+//	  method: PUT
+type ProjectConfig struct {
+	Process      string                 `yaml:"process"`
+	Cmd          []string               `yaml:"cmd"`
+	Resource     ResourceConfig         `yaml:"resource"`
+	Platforms    []string               `yaml:"platforms"`
+	CGO          CGOConfig              `yaml:"cgo"`
+	Output       string                 `yaml:"output"`
+	OutputRoot   string                 `yaml:"output_root"`
+	NameTemplate string                 `yaml:"name_template"`
+	Jobs         int                    `yaml:"jobs"`
+	Version      string                 `yaml:"version"`
+	Reproducible bool                   `yaml:"reproducible"`
+	RequireClean bool                   `yaml:"require_clean"`
+	Stamp        StampConfig            `yaml:"stamp"`
+	Deployment   map[string]interface{} `yaml:"deployment"`
+	Hooks        HooksConfig            `yaml:"hooks"`
+	Retention    RetentionConfig        `yaml:"retention"`
+	Publish      PublishConfig          `yaml:"publish"`
+	// Loaded reports whether gofar.yaml exists
+	Loaded bool `yaml:"-"`
+}
+
+type ResourceConfig struct {
+	Dir           string   `yaml:"dir"`
+	Include       []string `yaml:"include"`
+	Exclude       []string `yaml:"exclude"`
+	PreservePaths bool     `yaml:"preserve_paths"`
+	Symlinks      string   `yaml:"symlinks"`
+}
+
+type CGOConfig struct {
+	CC string `yaml:"cc"`
+}
+
+type StampConfig struct {
+	Package string `yaml:"package"`
+}
+
+// HooksConfig declares shell scripts run in the project base dir for each platform
+type HooksConfig struct {
+	PreBuild  []string `yaml:"pre_build"`
+	PostBuild []string `yaml:"post_build"`
+}
+
+// RetentionConfig is the default policy of gofar prune
+type RetentionConfig struct {
+	KeepLast     int    `yaml:"keep_last"`
+	KeepTagged   bool   `yaml:"keep_tagged"`
+	MaxAge       string `yaml:"max_age"`
+	MaxTotalSize string `yaml:"max_total_size"`
+}
+
+// Policy parses the ages and sizes of the config
+func (c RetentionConfig) Policy() (RetentionPolicy, error) {
+	policy := RetentionPolicy{KeepLast: c.KeepLast, KeepTagged: c.KeepTagged}
+	var err error
+	if len(c.MaxAge) > 0 {
+		if policy.MaxAge, err = ParseAge(c.MaxAge); err != nil {
+			return policy, fmt.Errorf("retention max_age : %w", err)
+		}
+	}
+	if len(c.MaxTotalSize) > 0 {
+		if policy.MaxTotalSize, err = ParseSize(c.MaxTotalSize); err != nil {
+			return policy, fmt.Errorf("retention max_total_size : %w", err)
+		}
+	}
+	return policy, nil
+}
+
+// PublishConfig is the default repository of gofar publish. credentials are never read from gofar.yaml
+type PublishConfig struct {
+	URL    string `yaml:"url"`
+	Method string `yaml:"method"`
+}
+
+// loadProjectConfig reads gofar.yaml in baseDir. a missing file yields an empty config
+func loadProjectConfig(baseDir string) (ProjectConfig, error) {
+	config := ProjectConfig{}
+	configFile := filepath.Join(baseDir, configFilename)
+	dat, err := os.ReadFile(configFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return config, fmt.Errorf("fail to read %s : %w", configFile, err)
+	}
+
+	err = yaml.Unmarshal(dat, &config)
+	if err != nil {
+		return config, fmt.Errorf("fail to parse %s : %w", configFile, err)
+	}
+
+	config.Loaded = true
+	return config, nil
+}
+
+// resolvePath returns path as is when absolute, otherwise relative to baseDir
+func resolvePath(baseDir, path string) string {
+	if len(path) == 0 || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
@@ -0,0 +1,105 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 3:50
+ */
+
+package far
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, configFilename), `
+process: saturn
+cmd: [saturn]
+resource:
+  include: ["*.yaml"]
+  exclude: [testdata]
+platforms: [linux_amd64]
+output: dist
+deployment:
+  team: platform
+`)
+
+	config, err := loadProjectConfig(root)
+	assert.Nil(t, err)
+	assert.Equal(t, "saturn", config.Process)
+	assert.Equal(t, []string{"linux_amd64"}, config.Platforms)
+	assert.Equal(t, filepath.Join(root, "dist"), resolvePath(root, config.Output))
+	assert.Equal(t, "platform", config.Deployment["team"])
+
+	rule := resourceRule{Include: config.Resource.Include, Exclude: config.Resource.Exclude}
+	selected, _ := rule.decide("conf/app.yaml", false)
+	assert.True(t, selected)
+	selected, _ = rule.decide("conf/app.json", false)
+	assert.False(t, selected)
+	selected, _ = rule.decide("testdata", true)
+	assert.False(t, selected)
+}
+
+func TestOptionsOverrideConfig(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/saturn\n")
+	writeTestFile(t, filepath.Join(root, "cmd", "saturn", "main.go"), "package main\n\nfunc main() {}\n")
+	writeTestFile(t, filepath.Join(root, configFilename), `
+process: saturn
+resource:
+  preserve_paths: true
+reproducible: true
+require_clean: true
+`)
+
+	b, err := NewBuilder(Options{WorkDir: root})
+	assert.Nil(t, err)
+	assert.True(t, b.PreserveResourcePaths)
+	assert.True(t, b.Reproducible)
+	assert.True(t, b.RequireClean)
+
+	off := false
+	b, err = NewBuilder(Options{WorkDir: root, PreserveResourcePaths: &off, Reproducible: &off, RequireClean: &off})
+	assert.Nil(t, err)
+	assert.False(t, b.PreserveResourcePaths)
+	assert.False(t, b.Reproducible)
+	assert.False(t, b.RequireClean)
+}
+
+func TestNewBuilderCmdErrors(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/saturn\n")
+	writeTestFile(t, filepath.Join(root, "main.go"), "package main\n\nfunc main() {}\n")
+	writeTestFile(t, filepath.Join(root, configFilename), "process: saturn\n")
+
+	_, err := NewBuilder(Options{WorkDir: root})
+	assert.True(t, errors.Is(err, ErrContext))
+
+	// gofar.yaml cmd entries do not need a cmd directory
+	writeTestFile(t, filepath.Join(root, "tools", "saturn", "main.go"), "package main\n\nfunc main() {}\n")
+	writeTestFile(t, filepath.Join(root, configFilename), "process: saturn\ncmd:\n  - tools/saturn\n")
+	b, err := NewBuilder(Options{WorkDir: root})
+	assert.Nil(t, err)
+	assert.Equal(t, []CmdRecord{{Path: filepath.Join(root, "tools", "saturn")}}, b.ProcessList)
+}
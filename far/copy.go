@@ -0,0 +1,107 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 9:30
+ */
+
+package far
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SymlinkPolicy decides how symbolic links among resources are packaged
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow copies the file or directory a link points to
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkPreserve stores the link itself. extraction refuses links which are
+	// absolute or resolve outside the far
+	SymlinkPreserve SymlinkPolicy = "preserve"
+	// SymlinkReject fails the resource step for every link
+	SymlinkReject SymlinkPolicy = "reject"
+)
+
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch SymlinkPolicy(s) {
+	case "":
+		return SymlinkFollow, nil
+	case SymlinkFollow, SymlinkPreserve, SymlinkReject:
+		return SymlinkPolicy(s), nil
+	}
+	return "", fmt.Errorf("invalid symlink policy (%s). use follow, preserve or reject", s)
+}
+
+// FileFailure is the failure of one resource file
+type FileFailure struct {
+	Path string
+	Err  error
+}
+
+// CopyError aggregates every resource file which failed to copy
+type CopyError struct {
+	Failures []FileFailure
+}
+
+func (e *CopyError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("fail to copy %d resource files", len(e.Failures)))
+	for _, v := range e.Failures {
+		sb.WriteString(fmt.Sprintf("\n - %s : %s", v.Path, v.Err.Error()))
+	}
+	return sb.String()
+}
+
+// copyResource copies source to target by policy keeping the permission bits of source
+func copyResource(source, target string, policy SymlinkPolicy) error {
+	info, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		switch policy {
+		case SymlinkReject:
+			return fmt.Errorf("symbolic link is rejected")
+		case SymlinkPreserve:
+			link, err := os.Readlink(source)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		}
+		if info, err = os.Stat(source); err != nil {
+			return err
+		}
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file (%s)", info.Mode().String())
+	}
+
+	if err = CopyFile(source, target); err != nil {
+		return err
+	}
+	return os.Chmod(target, info.Mode().Perm())
+}
@@ -0,0 +1,94 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 9:50
+ */
+
+package far
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newResourceDirFixture(t *testing.T) (string, string) {
+	root := t.TempDir()
+	resourceDir := filepath.Join(root, "resources")
+	writeTestFile(t, filepath.Join(resourceDir, ".env"), "A=1")
+	writeTestFile(t, filepath.Join(resourceDir, "conf dir", "app.yaml"), "a: 1")
+	writeTestFile(t, filepath.Join(resourceDir, "bin", "start.sh"), "#!/bin/sh")
+	assert.Nil(t, os.Chmod(filepath.Join(resourceDir, "bin", "start.sh"), 0750))
+	assert.Nil(t, os.Symlink("conf dir/app.yaml", filepath.Join(resourceDir, "current.yaml")))
+	assert.Nil(t, os.Symlink("bin", filepath.Join(resourceDir, "scripts")))
+	return root, resourceDir
+}
+
+func TestCopyResourceDir(t *testing.T) {
+	root, resourceDir := newResourceDirFixture(t)
+
+	b := &Builder{ProjectBaseDir: root, ResourceDir: resourceDir, SymlinkPolicy: SymlinkFollow,
+		workingDir: t.TempDir(), out: io.Discard}
+	assert.Nil(t, b.loadResourceFromDesginatedDir())
+
+	for _, name := range []string{".env", "conf dir/app.yaml", "current.yaml", "scripts/start.sh"} {
+		stat, err := os.Lstat(filepath.Join(b.workingDir, name))
+		assert.Nil(t, err, name)
+		assert.True(t, stat.Mode().IsRegular(), name)
+	}
+	stat, _ := os.Stat(filepath.Join(b.workingDir, "bin", "start.sh"))
+	assert.Equal(t, os.FileMode(0750), stat.Mode().Perm())
+
+	b.SymlinkPolicy = SymlinkPreserve
+	b.workingDir = t.TempDir()
+	assert.Nil(t, b.loadResourceFromDesginatedDir())
+	link, err := os.Readlink(filepath.Join(b.workingDir, "current.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "conf dir/app.yaml", link)
+
+	// links survive the far round trip
+	farPath := filepath.Join(t.TempDir(), "saturn.far")
+	assert.Nil(t, Zipit(b.workingDir, farPath))
+	farFile, err := Open(farPath)
+	assert.Nil(t, err)
+	defer farFile.Close()
+	assert.NotNil(t, farFile.Find("conf dir/app.yaml"))
+	extractDir := t.TempDir()
+	assert.Nil(t, farFile.Extract(extractDir))
+	link, err = os.Readlink(filepath.Join(extractDir, "scripts"))
+	assert.Nil(t, err)
+	assert.Equal(t, "bin", link)
+}
+
+func TestCopyResourceDirRejectSymlink(t *testing.T) {
+	root, resourceDir := newResourceDirFixture(t)
+
+	b := &Builder{ProjectBaseDir: root, ResourceDir: resourceDir, SymlinkPolicy: SymlinkReject,
+		workingDir: t.TempDir(), out: io.Discard}
+	err := b.loadResourceFromDesginatedDir()
+
+	var copyErr *CopyError
+	assert.True(t, errors.As(err, &copyErr))
+	assert.Len(t, copyErr.Failures, 2)
+}
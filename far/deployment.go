@@ -0,0 +1,206 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오후 1:30
+ */
+
+package far
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+// DeploymentSchemaVersion is the schema_version gofar writes. files written before
+// schema_version was introduced are version 0
+const DeploymentSchemaVersion = 1
+
+// DeploymentSchema is the JSON Schema of deployment.json
+//
+//go:embed deployment.schema.json
+var DeploymentSchema []byte
+
+// Deployment is the content of deployment.json at the root of a far
+type Deployment struct {
+	// SchemaVersion is DeploymentSchemaVersion when written, 0 for older files
+	SchemaVersion int `json:"schema_version"`
+	// Process is the exposed process binary name
+	Process string `json:"process"`
+	// ProcessType is GENERAL or USER_INTERACTIVE
+	ProcessType string `json:"process_type"`
+	// Platform is the GOOS_GOARCH target e.g) linux_amd64
+	Platform string `json:"platform,omitempty"`
+	// Version is the configured application version
+	Version string `json:"version,omitempty"`
+	// GoVersion is the go toolchain which compiled the process binary e.g) go1.22.4
+	GoVersion string `json:"go_version,omitempty"`
+	// Binaries lists every binary in the far
+	Binaries []DeploymentBinary `json:"binaries,omitempty"`
+	// Build describes who built the far, when and from which commit
+	Build DeploymentBuild `json:"build"`
+	// Extra holds fields declared under deployment of gofar.yaml and unknown fields of a read file
+	Extra map[string]interface{} `json:"-"`
+}
+
+// DeploymentBinary is a binary packaged in a far
+type DeploymentBinary struct {
+	// Name is the file name at the far root
+	Name string `json:"name"`
+	// Source is the cmd package directory, or the precompiled binary, relative to the project
+	Source string `json:"source,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	GOOS   string `json:"goos,omitempty"`
+	GOARCH string `json:"goarch,omitempty"`
+	CGO    bool   `json:"cgo"`
+	// Primary marks the exposed process binary
+	Primary bool `json:"primary"`
+}
+
+// DeploymentBuild is the build section of deployment.json
+type DeploymentBuild struct {
+	// Time is formatted as 2006-01-02 15:04:05 MST
+	Time string         `json:"time"`
+	User string         `json:"user"`
+	Git  *DeploymentGit `json:"git,omitempty"`
+}
+
+// DeploymentGit is the HEAD of the repository the far was built from
+type DeploymentGit struct {
+	Branch  string `json:"branch"`
+	Commit  string `json:"commit"`
+	Message string `json:"message"`
+	// Dirty reports the far was built with uncommitted changes listed in Modified and Untracked.
+	// nil when the worktree status could not be read
+	Dirty     *bool    `json:"dirty,omitempty"`
+	Modified  []string `json:"modified,omitempty"`
+	Untracked []string `json:"untracked,omitempty"`
+}
+
+// deploymentFields is the plain struct encoding/json uses for Deployment
+type deploymentFields Deployment
+
+// legacyDeploymentFields are fields written by older gofar
+type legacyDeploymentFields struct {
+	ExtraBin []string `json:"extra_bin"`
+}
+
+// MarshalJSON writes Extra fields next to the known ones
+func (d Deployment) MarshalJSON() ([]byte, error) {
+	dat, err := json.Marshal(deploymentFields(d))
+	if err != nil || len(d.Extra) == 0 {
+		return dat, err
+	}
+
+	m := make(map[string]interface{})
+	err = json.Unmarshal(dat, &m)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range d.Extra {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON reads known fields and keeps the others in Extra
+func (d *Deployment) UnmarshalJSON(dat []byte) error {
+	fields := deploymentFields{}
+	err := json.Unmarshal(dat, &fields)
+	if err != nil {
+		return err
+	}
+
+	m := make(map[string]interface{})
+	err = json.Unmarshal(dat, &m)
+	if err != nil {
+		return err
+	}
+	for _, k := range reservedDeploymentFields {
+		delete(m, k)
+	}
+	if fields.SchemaVersion == 0 {
+		legacy := legacyDeploymentFields{}
+		json.Unmarshal(dat, &legacy)
+		if len(fields.Binaries) == 0 && len(legacy.ExtraBin) > 0 {
+			fields.Binaries = append(fields.Binaries, DeploymentBinary{Name: fields.Process, Primary: true})
+			for _, v := range legacy.ExtraBin {
+				fields.Binaries = append(fields.Binaries, DeploymentBinary{Name: v})
+			}
+		}
+		delete(m, "extra_bin")
+	}
+	if len(m) > 0 {
+		fields.Extra = m
+	}
+	*d = Deployment(fields)
+	return nil
+}
+
+// reservedDeploymentFields are json names of Deployment fields, which gofar.yaml can not override
+var reservedDeploymentFields = []string{
+	"schema_version", "process", "process_type", "platform", "version", "go_version", "binaries", "build",
+}
+
+// isReservedDeploymentField reports whether name is a field of Deployment
+func isReservedDeploymentField(name string) bool {
+	for _, v := range reservedDeploymentFields {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseDeployment reads deployment.json of any schema version up to DeploymentSchemaVersion
+func ParseDeployment(dat []byte) (*Deployment, error) {
+	deployment := &Deployment{}
+	err := json.Unmarshal(dat, deployment)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse %s : %s", DeploymentFilename, err.Error())
+	}
+	if deployment.SchemaVersion > DeploymentSchemaVersion {
+		return nil, fmt.Errorf("%s schema_version %d is newer than supported %d",
+			DeploymentFilename, deployment.SchemaVersion, DeploymentSchemaVersion)
+	}
+	return deployment, nil
+}
+
+// Fields returns the deployment as generic json fields
+func (d Deployment) Fields() map[string]interface{} {
+	dat, _ := json.Marshal(d)
+	m := make(map[string]interface{})
+	json.Unmarshal(dat, &m)
+	return m
+}
+
+// Label returns the version, or the git commit when no version is recorded
+func (d Deployment) Label() string {
+	if len(d.Version) > 0 {
+		return d.Version
+	}
+	if d.Build.Git != nil {
+		return d.Build.Git.Commit
+	}
+	return ""
+}
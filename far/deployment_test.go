@@ -0,0 +1,129 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오후 2:00
+ */
+
+package far
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLegacyDeployment(t *testing.T) {
+	// written by gofar before schema_version was introduced
+	dat := []byte(`{"process":"saturn","process_type":"GENERAL","extra_bin":["saturn-cli"],` +
+		`"build":{"time":"2021-06-01 10:00:00 KST","user":"fatima",` +
+		`"git":{"branch":"main","commit":"abcdef012345","message":"fix\n"}},"team":"platform"}`)
+
+	deployment, err := ParseDeployment(dat)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, deployment.SchemaVersion)
+	assert.Equal(t, "saturn", deployment.Process)
+	assert.Equal(t, []DeploymentBinary{{Name: "saturn", Primary: true}, {Name: "saturn-cli"}}, deployment.Binaries)
+	assert.Equal(t, "abcdef012345", deployment.Build.Git.Commit)
+	assert.Equal(t, map[string]interface{}{"team": "platform"}, deployment.Extra)
+	assert.Equal(t, "abcdef012345", deployment.Label())
+}
+
+func TestDeploymentRoundTrip(t *testing.T) {
+	deployment := Deployment{
+		SchemaVersion: DeploymentSchemaVersion,
+		Process:       "saturn",
+		ProcessType:   procTypeGeneral,
+		Platform:      "linux_amd64",
+		Version:       "1.4.2",
+		Binaries:      []DeploymentBinary{{Name: "saturn"}},
+		Build:         DeploymentBuild{Time: "2026-10-18 14:00:00 UTC", User: "fatima"},
+		Extra:         map[string]interface{}{"team": "platform"},
+	}
+	dat, err := json.Marshal(deployment)
+	assert.Nil(t, err)
+	assert.Contains(t, string(dat), `"team":"platform"`)
+	assert.Contains(t, string(dat), `"schema_version":1`)
+
+	parsed, err := ParseDeployment(dat)
+	assert.Nil(t, err)
+	assert.Equal(t, deployment, *parsed)
+
+	_, err = ParseDeployment([]byte(`{"schema_version":99,"process":"saturn"}`))
+	assert.NotNil(t, err)
+}
+
+func TestDeploymentSchema(t *testing.T) {
+	schema := struct {
+		Required   []string               `json:"required"`
+		Properties map[string]interface{} `json:"properties"`
+	}{}
+	assert.Nil(t, json.Unmarshal(DeploymentSchema, &schema))
+
+	nameList := make([]string, 0)
+	for k := range schema.Properties {
+		nameList = append(nameList, k)
+	}
+	sort.Strings(nameList)
+	reserved := append([]string{}, reservedDeploymentFields...)
+	sort.Strings(reserved)
+	assert.Equal(t, reserved, nameList, "schema properties must match Deployment fields")
+	for _, v := range schema.Required {
+		assert.True(t, isReservedDeploymentField(v), v)
+	}
+}
+
+func TestDeploymentBinaries(t *testing.T) {
+	base := t.TempDir()
+	b := Builder{
+		ProjectBaseDir:    base,
+		ExposeProcessName: "saturn",
+		ProcessList: []CmdRecord{
+			{Path: filepath.Join(base, "cmd", "saturn")},
+			{Path: filepath.Join(base, "cmd", "saturn-cli")},
+		},
+		workingDir: t.TempDir(),
+	}
+	executable, err := os.Executable()
+	assert.Nil(t, err)
+	assert.Nil(t, CopyFile(executable, filepath.Join(b.workingDir, "saturn")))
+	writeTestFile(t, filepath.Join(b.workingDir, "saturn-cli"), "not a go binary")
+
+	binaryList, err := b.deploymentBinaries()
+	assert.Nil(t, err)
+	assert.Len(t, binaryList, 2)
+
+	assert.Equal(t, "saturn", binaryList[0].Name)
+	assert.Equal(t, "cmd/saturn", binaryList[0].Source)
+	assert.True(t, binaryList[0].Primary)
+	assert.Equal(t, runtime.GOOS, binaryList[0].GOOS)
+	assert.Equal(t, runtime.GOARCH, binaryList[0].GOARCH)
+	assert.Len(t, binaryList[0].SHA256, 64)
+
+	assert.Equal(t, "cmd/saturn-cli", binaryList[1].Source)
+	assert.False(t, binaryList[1].Primary)
+	assert.Equal(t, int64(len("not a go binary")), binaryList[1].Size)
+	// falls back to the target platform when the binary has no build information
+	assert.Equal(t, hostPlatform(), Platform{OS: binaryList[1].GOOS, Arch: binaryList[1].GOARCH})
+}
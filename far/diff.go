@@ -0,0 +1,407 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오전 10:20
+ */
+
+package far
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// textResourceExtensions are resources whose content changes are shown line by line
+var textResourceExtensions = []string{".properties", ".yaml", ".yml", ".xml", ".json"}
+
+// FarDiff is the difference between two fars
+type FarDiff struct {
+	Old        string         `json:"old"`
+	New        string         `json:"new"`
+	Deployment []FieldChange  `json:"deployment"`
+	Added      []string       `json:"added"`
+	Removed    []string       `json:"removed"`
+	Changed    []FileChange   `json:"changed"`
+	Binaries   []BinaryChange `json:"binaries"`
+}
+
+// Empty reports whether both fars have the same deployment, files and dependencies
+func (d FarDiff) Empty() bool {
+	return len(d.Deployment) == 0 && len(d.Added) == 0 && len(d.Removed) == 0 &&
+		len(d.Changed) == 0 && len(d.Binaries) == 0
+}
+
+// FieldChange is a deployment.json field, nested keys are joined with dots e.g) build.git.commit.
+// Old or New is nil when the field is absent
+type FieldChange struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// FileChange is a file whose content or mode differs. TextDiff is a unified
+// diff of text resources
+type FileChange struct {
+	Path      string `json:"path"`
+	OldSHA256 string `json:"old_sha256"`
+	NewSHA256 string `json:"new_sha256"`
+	OldMode   string `json:"old_mode"`
+	NewMode   string `json:"new_mode"`
+	TextDiff  string `json:"text_diff,omitempty"`
+}
+
+// BinaryChange is the difference of build information of a binary found in both fars
+type BinaryChange struct {
+	Path         string         `json:"path"`
+	OldGoVersion string         `json:"old_go_version"`
+	NewGoVersion string         `json:"new_go_version"`
+	Modules      []ModuleChange `json:"modules"`
+}
+
+// ModuleChange is a dependency module. Old or New is empty when the module is added or removed
+type ModuleChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// farContent is what Diff compares of a far
+type farContent struct {
+	deployment *Deployment
+	files      map[string]FileEntry
+	digests    map[string]string
+	binaries   map[string]BinaryInfo
+	file       *File
+}
+
+func readFarContent(farFile *File) (*farContent, error) {
+	content := &farContent{
+		files:    make(map[string]FileEntry),
+		digests:  make(map[string]string),
+		binaries: make(map[string]BinaryInfo),
+		file:     farFile,
+	}
+
+	var err error
+	content.deployment, err = farFile.ReadDeployment()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range farFile.Entries() {
+		if entry.Type == "dir" || entry.Path == DeploymentFilename || entry.Path == ManifestFilename {
+			continue
+		}
+		content.files[entry.Path] = entry
+	}
+	for _, file := range farFile.Files() {
+		if _, ok := content.files[file.Name]; !ok {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("fail to read %s : %w", file.Name, err)
+		}
+		content.digests[file.Name], _, err = digest(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("fail to read %s : %w", file.Name, err)
+		}
+
+		if isExecutableEntry(file.Name, file.Mode()) {
+			if info, err := farFile.ReadBinaryInfo(file.Name); err == nil {
+				content.binaries[file.Name] = *info
+			}
+		}
+	}
+	return content, nil
+}
+
+// Diff compares deployment.json, files and binary dependencies of two fars
+func Diff(oldPath, newPath string) (*FarDiff, error) {
+	oldFile, err := Open(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	defer oldFile.Close()
+	newFile, err := Open(newPath)
+	if err != nil {
+		return nil, err
+	}
+	defer newFile.Close()
+
+	oldContent, err := readFarContent(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	newContent, err := readFarContent(newFile)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &FarDiff{
+		Old:        oldPath,
+		New:        newPath,
+		Deployment: diffFields(flattenFields("", oldContent.deployment.Fields()), flattenFields("", newContent.deployment.Fields())),
+		Added:      make([]string, 0),
+		Removed:    make([]string, 0),
+		Changed:    make([]FileChange, 0),
+		Binaries:   make([]BinaryChange, 0),
+	}
+
+	for _, name := range sortedKeys(oldContent.files, newContent.files) {
+		oldEntry, inOld := oldContent.files[name]
+		newEntry, inNew := newContent.files[name]
+		switch {
+		case !inOld:
+			d.Added = append(d.Added, name)
+		case !inNew:
+			d.Removed = append(d.Removed, name)
+		case oldContent.digests[name] != newContent.digests[name] || oldEntry.Mode != newEntry.Mode:
+			change := FileChange{
+				Path:      name,
+				OldSHA256: oldContent.digests[name],
+				NewSHA256: newContent.digests[name],
+				OldMode:   oldEntry.Mode,
+				NewMode:   newEntry.Mode,
+			}
+			if change.OldSHA256 != change.NewSHA256 && isTextResource(name) {
+				change.TextDiff, err = diffTextEntry(oldContent.file, newContent.file, name)
+				if err != nil {
+					return nil, err
+				}
+			}
+			d.Changed = append(d.Changed, change)
+		}
+	}
+
+	for _, name := range sortedKeys(oldContent.binaries, newContent.binaries) {
+		oldInfo, inOld := oldContent.binaries[name]
+		newInfo, inNew := newContent.binaries[name]
+		if !inOld || !inNew {
+			continue
+		}
+		change := BinaryChange{
+			Path:         name,
+			OldGoVersion: oldInfo.GoVersion,
+			NewGoVersion: newInfo.GoVersion,
+			Modules:      diffModules(oldInfo.Deps, newInfo.Deps),
+		}
+		if change.OldGoVersion != change.NewGoVersion || len(change.Modules) > 0 {
+			d.Binaries = append(d.Binaries, change)
+		}
+	}
+	return d, nil
+}
+
+func sortedKeys[T any](maps ...map[string]T) []string {
+	keyMap := make(map[string]bool)
+	for _, m := range maps {
+		for k := range m {
+			keyMap[k] = true
+		}
+	}
+	keyList := make([]string, 0, len(keyMap))
+	for k := range keyMap {
+		keyList = append(keyList, k)
+	}
+	sort.Strings(keyList)
+	return keyList
+}
+
+// flattenFields joins nested keys of m with dots
+func flattenFields(prefix string, m map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	for k, v := range m {
+		key := k
+		if len(prefix) > 0 {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			for nk, nv := range flattenFields(key, nested) {
+				flat[nk] = nv
+			}
+			continue
+		}
+		flat[key] = v
+	}
+	return flat
+}
+
+func diffFields(oldFields, newFields map[string]interface{}) []FieldChange {
+	changeList := make([]FieldChange, 0)
+	for _, key := range sortedKeys(oldFields, newFields) {
+		oldValue, inOld := oldFields[key]
+		newValue, inNew := newFields[key]
+		if inOld && inNew && fmt.Sprint(oldValue) == fmt.Sprint(newValue) {
+			continue
+		}
+		changeList = append(changeList, FieldChange{Key: key, Old: oldValue, New: newValue})
+	}
+	return changeList
+}
+
+func diffModules(oldDeps, newDeps []ModuleVersion) []ModuleChange {
+	oldMap := make(map[string]string)
+	for _, v := range oldDeps {
+		oldMap[v.Path] = v.Version
+	}
+	newMap := make(map[string]string)
+	for _, v := range newDeps {
+		newMap[v.Path] = v.Version
+	}
+
+	changeList := make([]ModuleChange, 0)
+	for _, modPath := range sortedKeys(oldMap, newMap) {
+		if oldMap[modPath] != newMap[modPath] {
+			changeList = append(changeList, ModuleChange{Path: modPath, Old: oldMap[modPath], New: newMap[modPath]})
+		}
+	}
+	return changeList
+}
+
+func isTextResource(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, v := range textResourceExtensions {
+		if ext == v {
+			return true
+		}
+	}
+	return false
+}
+
+func diffTextEntry(oldFile, newFile *File, name string) (string, error) {
+	oldDat, err := oldFile.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	newDat, err := newFile.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(name, string(oldDat), string(newDat)), nil
+}
+
+// maxDiffCells bounds the LCS table of unifiedDiff
+const maxDiffCells = 4 * 1024 * 1024
+
+const diffContext = 2
+
+// unifiedDiff returns the line difference of oldText and newText in unified diff format
+func unifiedDiff(name, oldText, newText string) string {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return fmt.Sprintf("--- a/%s\n+++ b/%s\n(too large to diff)\n", name, name)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type diffLine struct {
+		op   byte
+		text string
+		// line numbers of a and b where the line is placed
+		ai, bi int
+	}
+	lineList := make([]diffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lineList = append(lineList, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lineList = append(lineList, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lineList = append(lineList, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", name, name))
+	for start := 0; start < len(lineList); {
+		if lineList[start].op == ' ' {
+			start++
+			continue
+		}
+		// hunk spans changed lines closer than 2*diffContext plus context around them
+		end := start
+		for k := start; k < len(lineList); k++ {
+			if lineList[k].op != ' ' {
+				end = k
+			} else if k-end > 2*diffContext {
+				break
+			}
+		}
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+		to := end + diffContext + 1
+		if to > len(lineList) {
+			to = len(lineList)
+		}
+
+		oldCount, newCount := 0, 0
+		for _, v := range lineList[from:to] {
+			if v.op != '+' {
+				oldCount++
+			}
+			if v.op != '-' {
+				newCount++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", lineList[from].ai+1, oldCount, lineList[from].bi+1, newCount))
+		for _, v := range lineList[from:to] {
+			sb.WriteByte(v.op)
+			sb.WriteString(v.text)
+			sb.WriteByte('\n')
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+func splitLines(text string) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
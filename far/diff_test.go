@@ -0,0 +1,94 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오전 11:00
+ */
+
+package far
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFar(t *testing.T, files map[string]string) string {
+	source := t.TempDir()
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(source, filepath.FromSlash(name)), content)
+	}
+	farPath := filepath.Join(t.TempDir(), "saturn.far")
+	assert.Nil(t, Zipit(source, farPath))
+	return farPath
+}
+
+func TestDiff(t *testing.T) {
+	oldFar := writeTestFar(t, map[string]string{
+		DeploymentFilename:   `{"process":"saturn","build":{"user":"fatima","git":{"commit":"aaaa"}}}`,
+		"conf/app.yaml":      "name: saturn\nport: 8080\nlevel: info\n",
+		"removed.properties": "a=1\n",
+		"same.xml":           "<a/>\n",
+	})
+	newFar := writeTestFar(t, map[string]string{
+		DeploymentFilename: `{"process":"saturn","version":"1.4.2","build":{"user":"fatima","git":{"commit":"bbbb"}}}`,
+		"conf/app.yaml":    "name: saturn\nport: 9090\nlevel: info\n",
+		"added.json":       "{}",
+		"same.xml":         "<a/>\n",
+	})
+
+	d, err := Diff(oldFar, newFar)
+	assert.Nil(t, err)
+	assert.False(t, d.Empty())
+	assert.Equal(t, []FieldChange{
+		{Key: "build.git.commit", Old: "aaaa", New: "bbbb"},
+		{Key: "version", Old: nil, New: "1.4.2"},
+	}, d.Deployment)
+	assert.Equal(t, []string{"added.json"}, d.Added)
+	assert.Equal(t, []string{"removed.properties"}, d.Removed)
+	assert.Len(t, d.Changed, 1)
+	assert.Equal(t, "conf/app.yaml", d.Changed[0].Path)
+	assert.Equal(t, "--- a/conf/app.yaml\n+++ b/conf/app.yaml\n"+
+		"@@ -1,3 +1,3 @@\n name: saturn\n-port: 8080\n+port: 9090\n level: info\n", d.Changed[0].TextDiff)
+
+	d, err = Diff(oldFar, oldFar)
+	assert.Nil(t, err)
+	assert.True(t, d.Empty())
+}
+
+func TestDiffModules(t *testing.T) {
+	changeList := diffModules(
+		[]ModuleVersion{{"github.com/go-git/go-git/v5", "v5.4.2"}, {"gopkg.in/yaml.v3", "v3.0.0"}},
+		[]ModuleVersion{{"github.com/go-git/go-git/v5", "v5.5.0"}, {"github.com/stretchr/testify", "v1.7.0"}},
+	)
+	assert.Equal(t, []ModuleChange{
+		{Path: "github.com/go-git/go-git/v5", Old: "v5.4.2", New: "v5.5.0"},
+		{Path: "github.com/stretchr/testify", Old: "", New: "v1.7.0"},
+		{Path: "gopkg.in/yaml.v3", Old: "v3.0.0", New: ""},
+	}, changeList)
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	oldText := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	newText := "1\nTWO\n3\n4\n5\n6\n7\n8\nNINE\n10\n"
+	assert.Equal(t, "--- a/x.properties\n+++ b/x.properties\n"+
+		"@@ -1,4 +1,4 @@\n 1\n-2\n+TWO\n 3\n 4\n"+
+		"@@ -7,4 +7,4 @@\n 7\n 8\n-9\n+NINE\n 10\n", unifiedDiff("x.properties", oldText, newText))
+}
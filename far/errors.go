@@ -0,0 +1,61 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 5:10
+ */
+
+package far
+
+import (
+	"errors"
+)
+
+// packaging failure classes. use errors.Is to classify an error returned by
+// NewBuilder or Builder.Build
+var (
+	ErrContext    = errors.New("build context error")
+	ErrCompile    = errors.New("compile error")
+	ErrResource   = errors.New("resource copy error")
+	ErrDeployment = errors.New("deployment.json error")
+	ErrCompress   = errors.New("compress error")
+	ErrHook       = errors.New("hook error")
+)
+
+// PackagingError wraps the cause of a failure together with its failure class
+type PackagingError struct {
+	Class error
+	Err   error
+}
+
+func newPackagingError(class, err error) error {
+	return &PackagingError{Class: class, Err: err}
+}
+
+func (e *PackagingError) Error() string {
+	return e.Class.Error() + " : " + e.Err.Error()
+}
+
+func (e *PackagingError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PackagingError) Is(target error) bool {
+	return target == e.Class
+}
@@ -18,23 +18,29 @@
  *
  * @project fatima
  * @author DeockJin Chung (jin.freestyle@gmail.com)
- * @date 22. 8. 22. 오후 9:02
+ * @date 26. 10. 17. 오후 5:30
  */
 
-package main
+package far
 
 import (
+	"errors"
 	"fmt"
-	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
-func TestReadGitInfo(t *testing.T) {
-	path, _ := os.Getwd()
-	gitInfo := readGitInfo(path)
-	assert.True(t, gitInfo.Valid, "git info is not valid")
-	fmt.Printf("BranchName : %s\n", gitInfo.BranchName)
-	fmt.Printf("CommitHash : %s\n", gitInfo.CommitHash)
-	fmt.Printf("LastCommitMessage : %s\n", gitInfo.LastCommitMessage)
+func TestPackagingError(t *testing.T) {
+	cause := &os.PathError{Op: "open", Path: "/nowhere", Err: os.ErrNotExist}
+	err := newPackagingError(ErrResource, fmt.Errorf("fail to copy resource : %w", cause))
+
+	assert.True(t, errors.Is(err, ErrResource))
+	assert.False(t, errors.Is(err, ErrCompile))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	var pathErr *os.PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/nowhere", pathErr.Path)
 }
@@ -0,0 +1,279 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 4:30
+ */
+
+package far
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const DeploymentFilename = "deployment.json"
+
+// File is an opened far artifact
+type File struct {
+	Path   string
+	reader *zip.ReadCloser
+}
+
+func Open(path string) (*File, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open far %s : %s", path, err.Error())
+	}
+	return &File{Path: path, reader: reader}, nil
+}
+
+func (f *File) Close() error {
+	return f.reader.Close()
+}
+
+func (f *File) Files() []*zip.File {
+	return f.reader.File
+}
+
+func (f *File) Find(name string) *zip.File {
+	for _, file := range f.reader.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+func (f *File) ReadFile(name string) ([]byte, error) {
+	file := f.Find(name)
+	if file == nil {
+		return nil, fmt.Errorf("not found %s in %s", name, f.Path)
+	}
+
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// ReadDeployment parses deployment.json of the far
+func (f *File) ReadDeployment() (*Deployment, error) {
+	dat, err := f.ReadFile(DeploymentFilename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeployment(dat)
+}
+
+// Extract writes every entry under targetDir keeping file modes. symbolic links are
+// created after regular files and must resolve inside targetDir
+func (f *File) Extract(targetDir string) error {
+	err := EnsureDirectory(targetDir)
+	if err != nil {
+		return err
+	}
+	targetDir, err = filepath.Abs(targetDir)
+	if err != nil {
+		return err
+	}
+
+	linkList := make([]*zip.File, 0)
+	for _, file := range f.reader.File {
+		target, err := safeJoin(targetDir, file.Name)
+		if err != nil {
+			return err
+		}
+		if err = checkNoSymlinkParent(targetDir, target); err != nil {
+			return fmt.Errorf("fail to extract %s : %w", file.Name, err)
+		}
+
+		if file.FileInfo().IsDir() {
+			if err = EnsureDirectory(target); err != nil {
+				return err
+			}
+			continue
+		}
+		if file.Mode()&os.ModeSymlink != 0 {
+			linkList = append(linkList, file)
+			continue
+		}
+
+		if err = EnsureDirectory(filepath.Dir(target)); err != nil {
+			return err
+		}
+		if err = extractZipFile(file, target); err != nil {
+			return fmt.Errorf("fail to extract %s : %s", file.Name, err.Error())
+		}
+	}
+
+	return extractSymlinks(targetDir, linkList)
+}
+
+func extractZipFile(file *zip.File, target string) error {
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// never write through an existing file which may be a symbolic link
+	if err = os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	w, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, file.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(target, file.Mode().Perm())
+}
+
+// extractSymlinks creates links and checks them again once all exist,
+// as a link may point through another link created later
+func extractSymlinks(targetDir string, linkList []*zip.File) error {
+	created := make([]string, 0, len(linkList))
+	fail := func(name string, err error) error {
+		for _, v := range created {
+			os.Remove(v)
+		}
+		return fmt.Errorf("fail to extract %s : %w", name, err)
+	}
+
+	linkTargets := make([]string, len(linkList))
+	for i, file := range linkList {
+		target, _ := safeJoin(targetDir, file.Name)
+		link, err := readSymlink(file)
+		if err != nil {
+			return fail(file.Name, err)
+		}
+		if err = checkSymlink(targetDir, target, link); err != nil {
+			return fail(file.Name, err)
+		}
+		if err = EnsureDirectory(filepath.Dir(target)); err != nil {
+			return fail(file.Name, err)
+		}
+		if err = checkNoSymlinkParent(targetDir, target); err != nil {
+			return fail(file.Name, err)
+		}
+		if err = os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return fail(file.Name, err)
+		}
+		if err = os.Symlink(link, target); err != nil {
+			return fail(file.Name, err)
+		}
+		created = append(created, target)
+		linkTargets[i] = link
+	}
+
+	for i, file := range linkList {
+		target, _ := safeJoin(targetDir, file.Name)
+		if err := checkNoSymlinkParent(targetDir, target); err != nil {
+			return fail(file.Name, err)
+		}
+		if err := checkSymlink(targetDir, target, linkTargets[i]); err != nil {
+			return fail(file.Name, err)
+		}
+	}
+	return nil
+}
+
+func readSymlink(file *zip.File) (string, error) {
+	r, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	link, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(link), nil
+}
+
+// checkSymlink refuses a link at path whose target is absolute, leaves baseDir
+// or passes through another symbolic link before its last element
+func checkSymlink(baseDir, path, link string) error {
+	if len(link) == 0 || filepath.IsAbs(link) || filepath.VolumeName(link) != "" {
+		return fmt.Errorf("symbolic link to %q is not relative", link)
+	}
+
+	current := filepath.Dir(path)
+	elemList := strings.Split(filepath.ToSlash(link), "/")
+	for i, elem := range elemList {
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+		default:
+			current = filepath.Join(current, elem)
+		}
+		if !isWithin(baseDir, current) {
+			return fmt.Errorf("symbolic link to %q leaves %s", link, baseDir)
+		}
+		if i == len(elemList)-1 {
+			break
+		}
+		if info, err := os.Lstat(current); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("symbolic link to %q passes through symbolic link %s", link, current)
+		}
+	}
+	return nil
+}
+
+// checkNoSymlinkParent refuses path when a directory between baseDir and path is a symbolic link
+func checkNoSymlinkParent(baseDir, path string) error {
+	for dir := filepath.Dir(path); isWithin(baseDir, dir) && dir != baseDir; dir = filepath.Dir(dir) {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symbolic link", dir)
+		}
+	}
+	return nil
+}
+
+func isWithin(baseDir, path string) bool {
+	return path == baseDir || strings.HasPrefix(path, baseDir+string(os.PathSeparator))
+}
+
+// safeJoin refuses entry names escaping baseDir (zip slip)
+func safeJoin(baseDir, name string) (string, error) {
+	target := filepath.Join(baseDir, filepath.FromSlash(name))
+	if target != filepath.Clean(baseDir) &&
+		!strings.HasPrefix(target, filepath.Clean(baseDir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid entry name : %s", name)
+	}
+	return target, nil
+}
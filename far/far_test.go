@@ -0,0 +1,121 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 20. 오전 10:30
+ */
+
+package far
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEntry struct {
+	name string
+	link string
+	data string
+}
+
+// writeCraftedFar writes entries in the given order, links as symbolic link entries
+func writeCraftedFar(t *testing.T, entries []testEntry) *File {
+	farPath := filepath.Join(t.TempDir(), "crafted.far")
+	f, err := os.Create(farPath)
+	assert.Nil(t, err)
+	w := zip.NewWriter(f)
+	for _, v := range entries {
+		header := &zip.FileHeader{Name: v.name, Method: zip.Deflate}
+		content := v.data
+		if len(v.link) > 0 {
+			header.SetMode(os.ModeSymlink | 0777)
+			content = v.link
+		} else {
+			header.SetMode(0644)
+		}
+		fw, err := w.CreateHeader(header)
+		assert.Nil(t, err)
+		_, err = fw.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, w.Close())
+	assert.Nil(t, f.Close())
+
+	farFile, err := Open(farPath)
+	assert.Nil(t, err)
+	t.Cleanup(func() { farFile.Close() })
+	return farFile
+}
+
+func TestExtractRefusesEscapingSymlink(t *testing.T) {
+	outside := t.TempDir()
+	cases := map[string][]testEntry{
+		"absolute link": {
+			{name: "evil", link: outside},
+			{name: "evil/pwned", data: "pwned"},
+		},
+		"relative link": {
+			{name: "evil", link: "../../" + filepath.Base(outside)},
+		},
+		"link through link": {
+			{name: "x", link: "y/.."},
+			{name: "y", link: "."},
+		},
+		"entry through existing link": {
+			{name: "conf/app.yaml", data: "name: saturn\n"},
+			{name: "evil", link: "conf"},
+			{name: "evil/pwned", data: "pwned"},
+		},
+	}
+
+	for name, entries := range cases {
+		t.Run(name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			err := writeCraftedFar(t, entries).Extract(targetDir)
+			assert.NotNil(t, err)
+			_, err = os.Stat(filepath.Join(outside, "pwned"))
+			assert.True(t, os.IsNotExist(err))
+			if info, err := os.Lstat(filepath.Join(targetDir, "evil")); err == nil {
+				assert.Zero(t, info.Mode()&os.ModeSymlink, "refused link is not left behind")
+			}
+		})
+	}
+}
+
+func TestExtractSymlink(t *testing.T) {
+	targetDir := t.TempDir()
+	farFile := writeCraftedFar(t, []testEntry{
+		{name: "lib/libsaturn.so", link: "libsaturn.so.1"},
+		{name: "lib/libsaturn.so.1", data: "library"},
+		{name: "conf", link: "lib"},
+		{name: "bin/lib", link: "../lib"},
+	})
+	assert.Nil(t, farFile.Extract(targetDir))
+
+	dat, err := os.ReadFile(filepath.Join(targetDir, "bin", "lib", "libsaturn.so"))
+	assert.Nil(t, err)
+	assert.Equal(t, "library", string(dat))
+	link, err := os.Readlink(filepath.Join(targetDir, "conf"))
+	assert.Nil(t, err)
+	assert.Equal(t, "lib", link)
+}
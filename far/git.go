@@ -0,0 +1,310 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 22. 8. 22. 오후 8:47
+ */
+
+package far
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type GitInfo struct {
+	Valid             bool
+	BranchName        string
+	CommitHash        string
+	LastCommitMessage string
+	// CommitTime is the committer time of HEAD
+	CommitTime time.Time
+	// StatusKnown reports Dirty, Modified and Untracked were read from the worktree
+	StatusKnown bool
+	// Dirty reports uncommitted changes in the worktree
+	Dirty bool
+	// Modified lists changed, added or deleted files relative to the repository root
+	Modified []string
+	// Untracked lists files git does not track, ignored files excluded
+	Untracked []string
+	// Tag is the nearest tag reachable from HEAD, empty when there is none
+	Tag string
+	// TagDistance is the number of commits reachable from HEAD but not from Tag
+	TagDistance int
+	// AbbrevHash is the 7 character hash of HEAD
+	AbbrevHash string
+}
+
+// Describe returns the version of HEAD in git describe style e.g) v1.4.2, v1.4.2-3-gabc1234-dirty.
+// like git describe --dirty, untracked files do not make it dirty. empty when no tag is reachable
+func (g GitInfo) Describe() string {
+	if len(g.Tag) == 0 {
+		return ""
+	}
+	version := g.Tag
+	if g.TagDistance > 0 {
+		version = fmt.Sprintf("%s-%d-g%s", g.Tag, g.TagDistance, g.AbbrevHash)
+	}
+	if len(g.Modified) > 0 {
+		version += "-dirty"
+	}
+	return version
+}
+
+// DirtyFiles returns modified and untracked files
+func (g GitInfo) DirtyFiles() []string {
+	return append(append([]string{}, g.Modified...), g.Untracked...)
+}
+
+func (g GitInfo) ToMap() map[string]string {
+	m := make(map[string]string)
+	m["branch"] = g.BranchName
+	m["commit"] = g.CommitHash
+	m["message"] = g.LastCommitMessage
+	return m
+}
+
+// readGitInfo reads HEAD of the repository containing baseDir. changes under
+// ignoreDirs, the directories gofar writes artifacts to, are left out of the status
+func readGitInfo(baseDir string, ignoreDirs ...string) (GitInfo, error) {
+	gitInfo := GitInfo{Valid: false}
+	gitRepo, err := git.PlainOpenWithOptions(baseDir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return gitInfo, fmt.Errorf("fail to open git %s : %w", baseDir, err)
+	}
+
+	// retrieve head
+	ref, err := gitRepo.Head()
+	if err != nil {
+		return gitInfo, fmt.Errorf("repo.Head error : %w", err)
+	}
+
+	gitInfo.BranchName = ref.Name().String()
+	// BranchName likes : refs/heads/enhancement/git_commit_message
+	if len(gitInfo.BranchName) > 11 {
+		gitInfo.BranchName = gitInfo.BranchName[11:]
+	}
+	// ... retrieves the commit history
+	gitInfo.CommitHash = ref.Hash().String()
+	if len(gitInfo.CommitHash) > 12 {
+		gitInfo.CommitHash = gitInfo.CommitHash[:12]
+	}
+	cIter, err := gitRepo.Log(&git.LogOptions{From: ref.Hash()})
+	if err != nil {
+		return gitInfo, fmt.Errorf("reference log loading error : %w", err)
+	}
+
+	gitInfo.Valid = true
+	commit, err := cIter.Next()
+	if err != nil {
+		return gitInfo, fmt.Errorf("commit log iterating : %w", err)
+	}
+	gitInfo.LastCommitMessage = commit.Message
+	gitInfo.CommitTime = commit.Committer.When
+
+	gitInfo.AbbrevHash = ref.Hash().String()[:7]
+	// the worktree status does not depend on the history, which a shallow clone lacks
+	err = readWorktreeStatus(gitRepo, &gitInfo, ignoreDirs)
+	if err != nil {
+		return gitInfo, err
+	}
+
+	err = describeHead(gitRepo, ref.Hash(), &gitInfo)
+	if err != nil {
+		return gitInfo, err
+	}
+	return gitInfo, nil
+}
+
+// readTags maps commit hashes to annotated and lightweight tag names pointing to them
+func readTags(gitRepo *git.Repository) (map[plumbing.Hash][]string, error) {
+	tagMap := make(map[plumbing.Hash][]string)
+	tagRefs, err := gitRepo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	err = tagRefs.ForEach(func(ref *plumbing.Reference) error {
+		commitHash := ref.Hash()
+		// annotated tag points to a tag object
+		if tag, err := gitRepo.TagObject(ref.Hash()); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				// tag of a tree or blob
+				return nil
+			}
+			commitHash = commit.Hash
+		}
+		tagMap[commitHash] = append(tagMap[commitHash], ref.Name().Short())
+		return nil
+	})
+	return tagMap, err
+}
+
+// describeHead finds the tag nearest to head in breadth first order. when a commit
+// has several tags the greatest name wins. history beyond the boundary of a shallow
+// clone is missing, where no tag is found
+func describeHead(gitRepo *git.Repository, head plumbing.Hash, gitInfo *GitInfo) error {
+	tagMap, err := readTags(gitRepo)
+	if err != nil {
+		return fmt.Errorf("fail to read tags : %w", err)
+	}
+	if len(tagMap) == 0 {
+		return nil
+	}
+
+	var tagged *object.Commit
+	visited := map[plumbing.Hash]bool{head: true}
+	queue := []plumbing.Hash{head}
+	for len(queue) > 0 && tagged == nil {
+		commit, err := gitRepo.CommitObject(queue[0])
+		queue = queue[1:]
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("fail to read commit : %w", err)
+		}
+		if _, ok := tagMap[commit.Hash]; ok {
+			tagged = commit
+			break
+		}
+		for _, parent := range commit.ParentHashes {
+			if !visited[parent] {
+				visited[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+	if tagged == nil {
+		return nil
+	}
+
+	nameList := tagMap[tagged.Hash]
+	sort.Strings(nameList)
+	gitInfo.Tag = nameList[len(nameList)-1]
+	gitInfo.TagDistance, err = countCommitsSince(gitRepo, head, tagged)
+	return err
+}
+
+// countCommitsSince returns the number of commits reachable from head but not from base
+func countCommitsSince(gitRepo *git.Repository, head plumbing.Hash, base *object.Commit) (int, error) {
+	baseAncestors, err := reachableCommits(gitRepo, base.Hash, nil)
+	if err != nil {
+		return 0, err
+	}
+	headAncestors, err := reachableCommits(gitRepo, head, baseAncestors)
+	if err != nil {
+		return 0, err
+	}
+	return len(headAncestors), nil
+}
+
+// reachableCommits returns commits reachable from start without passing through stop.
+// commits missing beyond a shallow clone boundary are skipped
+func reachableCommits(gitRepo *git.Repository, start plumbing.Hash, stop map[plumbing.Hash]bool) (map[plumbing.Hash]bool, error) {
+	reached := make(map[plumbing.Hash]bool)
+	queue := []plumbing.Hash{start}
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+		if reached[hash] || stop[hash] {
+			continue
+		}
+		commit, err := gitRepo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fail to read commit : %w", err)
+		}
+		reached[hash] = true
+		queue = append(queue, commit.ParentHashes...)
+	}
+	return reached, nil
+}
+
+// readWorktreeStatus records uncommitted changes of the worktree into gitInfo
+func readWorktreeStatus(gitRepo *git.Repository, gitInfo *GitInfo, ignoreDirs []string) error {
+	worktree, err := gitRepo.Worktree()
+	if err != nil {
+		return fmt.Errorf("fail to open worktree : %w", err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return fmt.Errorf("fail to read worktree status : %w", err)
+	}
+
+	ignorePrefixes := worktreePrefixes(worktree.Filesystem.Root(), ignoreDirs)
+	for path, fileStatus := range status {
+		if hasAnyPrefix(path, ignorePrefixes) {
+			continue
+		}
+		switch {
+		case fileStatus.Worktree == git.Untracked:
+			gitInfo.Untracked = append(gitInfo.Untracked, path)
+		case fileStatus.Worktree != git.Unmodified || fileStatus.Staging != git.Unmodified:
+			gitInfo.Modified = append(gitInfo.Modified, path)
+		}
+	}
+	sort.Strings(gitInfo.Modified)
+	sort.Strings(gitInfo.Untracked)
+	gitInfo.Dirty = len(gitInfo.Modified) > 0 || len(gitInfo.Untracked) > 0
+	gitInfo.StatusKnown = true
+	return nil
+}
+
+// worktreePrefixes returns slash separated prefixes of dirs inside the worktree root
+func worktreePrefixes(root string, dirs []string) []string {
+	prefixes := make([]string, 0)
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+	for _, dir := range dirs {
+		if len(dir) == 0 {
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = real
+		}
+		rel, err := filepath.Rel(realRoot, dir)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		prefixes = append(prefixes, filepath.ToSlash(rel)+"/")
+	}
+	return prefixes
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
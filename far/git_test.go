@@ -0,0 +1,240 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 22. 8. 22. 오후 9:02
+ */
+
+package far
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadGitInfo(t *testing.T) {
+	path, _ := os.Getwd()
+	gitInfo, err := readGitInfo(path)
+	assert.Nil(t, err)
+	assert.True(t, gitInfo.Valid, "git info is not valid")
+	fmt.Printf("BranchName : %s\n", gitInfo.BranchName)
+	fmt.Printf("CommitHash : %s\n", gitInfo.CommitHash)
+	fmt.Printf("LastCommitMessage : %s\n", gitInfo.LastCommitMessage)
+}
+
+func TestReadGitInfoWorktreeStatus(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	assert.Nil(t, err)
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/saturn\n")
+	writeTestFile(t, filepath.Join(root, ".gitignore"), "dist/\n")
+	worktree, err := repo.Worktree()
+	assert.Nil(t, err)
+	_, err = worktree.Add(".")
+	assert.Nil(t, err)
+	commitTime := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	_, err = worktree.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "fatima", Email: "fatima@example.com", When: commitTime},
+	})
+	assert.Nil(t, err)
+
+	writeTestFile(t, filepath.Join(root, "dist", "saturn.far"), "ignored")
+	gitInfo, err := readGitInfo(root)
+	assert.Nil(t, err)
+	assert.False(t, gitInfo.Dirty)
+	assert.True(t, gitInfo.CommitTime.Equal(commitTime))
+	assert.Nil(t, checkCleanTree(gitInfo))
+
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/jupiter\n")
+	writeTestFile(t, filepath.Join(root, "notes.txt"), "todo")
+	gitInfo, err = readGitInfo(root)
+	assert.Nil(t, err)
+	assert.True(t, gitInfo.Dirty)
+	assert.Equal(t, []string{"go.mod"}, gitInfo.Modified)
+	assert.Equal(t, []string{"notes.txt"}, gitInfo.Untracked)
+	assert.NotNil(t, checkCleanTree(gitInfo))
+	assert.NotNil(t, checkCleanTree(GitInfo{}))
+	assert.NotNil(t, checkCleanTree(GitInfo{Valid: true}), "unknown status is not clean")
+
+	// outside of git the build fails only when a clean tree is required
+	b := Builder{ProjectBaseDir: t.TempDir(), out: io.Discard}
+	assert.Nil(t, b.collectBuildInfo())
+	assert.False(t, b.buildInfo.Git.StatusKnown)
+	b.RequireClean = true
+	assert.NotNil(t, b.collectBuildInfo())
+}
+
+func TestDescribe(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	assert.Nil(t, err)
+	worktree, err := repo.Worktree()
+	assert.Nil(t, err)
+	signature := &object.Signature{Name: "fatima", Email: "fatima@example.com", When: time.Now()}
+	commit := func(content string) plumbing.Hash {
+		writeTestFile(t, filepath.Join(root, "version.txt"), content)
+		_, err := worktree.Add("version.txt")
+		assert.Nil(t, err)
+		hash, err := worktree.Commit(content, &git.CommitOptions{Author: signature})
+		assert.Nil(t, err)
+		return hash
+	}
+
+	commit("1")
+	gitInfo, err := readGitInfo(root)
+	assert.Nil(t, err)
+	assert.Equal(t, "", gitInfo.Describe(), "no tag")
+
+	tagged := commit("2")
+	_, err = repo.CreateTag("v1.4.2", tagged, &git.CreateTagOptions{Tagger: signature, Message: "release"})
+	assert.Nil(t, err)
+	gitInfo, err = readGitInfo(root)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.4.2", gitInfo.Describe())
+
+	commit("3")
+	head := commit("4")
+	gitInfo, err = readGitInfo(root)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.4.2-2-g"+head.String()[:7], gitInfo.Describe())
+
+	// untracked files are dirty but do not change the version, like git describe --dirty
+	writeTestFile(t, filepath.Join(root, "notes.txt"), "todo")
+	gitInfo, err = readGitInfo(root)
+	assert.Nil(t, err)
+	assert.True(t, gitInfo.Dirty)
+	assert.Equal(t, "v1.4.2-2-g"+head.String()[:7], gitInfo.Describe())
+	assert.Nil(t, os.Remove(filepath.Join(root, "notes.txt")))
+
+	// artifacts of gofar inside the worktree are not changes of the project
+	writeTestFile(t, filepath.Join(root, "dist", "saturn-v1.4.2.far"), "far")
+	writeTestFile(t, filepath.Join(root, "dist", IndexFilename), "{}")
+	gitInfo, err = readGitInfo(root, filepath.Join(root, "dist"))
+	assert.Nil(t, err)
+	assert.False(t, gitInfo.Dirty)
+	assert.Nil(t, os.RemoveAll(filepath.Join(root, "dist")))
+
+	writeTestFile(t, filepath.Join(root, "version.txt"), "dirty")
+	gitInfo, err = readGitInfo(root)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.4.2-2-g"+head.String()[:7]+"-dirty", gitInfo.Describe())
+
+	// lightweight tag
+	_, err = repo.CreateTag("v1.5.0", head, nil)
+	assert.Nil(t, err)
+	gitInfo, err = readGitInfo(root)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.5.0-dirty", gitInfo.Describe())
+}
+
+func TestDescribeShallowClone(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	assert.Nil(t, err)
+	worktree, err := repo.Worktree()
+	assert.Nil(t, err)
+	signature := &object.Signature{Name: "fatima", Email: "fatima@example.com", When: time.Now()}
+	commit := func(content string) plumbing.Hash {
+		writeTestFile(t, filepath.Join(root, "version.txt"), content)
+		_, err := worktree.Add("version.txt")
+		assert.Nil(t, err)
+		hash, err := worktree.Commit(content, &git.CommitOptions{Author: signature})
+		assert.Nil(t, err)
+		return hash
+	}
+
+	_, err = repo.CreateTag("v1.0.0", commit("1"), nil)
+	assert.Nil(t, err)
+	boundary := commit("2")
+	head := commit("3")
+	// cut the history like git clone --depth 1
+	hex := boundary.String()
+	assert.Nil(t, os.Remove(filepath.Join(root, ".git", "objects", hex[:2], hex[2:])))
+	writeTestFile(t, filepath.Join(root, "version.txt"), "modified")
+
+	gitInfo, err := readGitInfo(root)
+	assert.Nil(t, err)
+	assert.Equal(t, "", gitInfo.Tag, "tag beyond the boundary is not found")
+	assert.True(t, gitInfo.Dirty)
+	assert.Equal(t, []string{"version.txt"}, gitInfo.Modified)
+	assert.NotNil(t, checkCleanTree(gitInfo))
+
+	_, err = repo.CreateTag("v1.1.0", head, nil)
+	assert.Nil(t, err)
+	gitInfo, err = readGitInfo(root)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.1.0-dirty", gitInfo.Describe())
+}
+
+func TestFarPathWithVersion(t *testing.T) {
+	b := Builder{ExposeProcessName: "saturn", OutputDir: "/dist", Version: "release/1.4.2-3-gabc1234"}
+	assert.Equal(t, filepath.Join("/dist", "saturn-release_1.4.2-3-gabc1234.far"), b.GetFarPath())
+
+	b.Platforms = []Platform{{OS: "linux", Arch: "amd64"}, {OS: "linux", Arch: "arm64"}}
+	b.setTarget(b.Platforms[1])
+	assert.Equal(t, filepath.Join("/dist", "saturn-release_1.4.2-3-gabc1234-linux_arm64.far"), b.GetFarPath())
+
+	b.Version = ""
+	assert.Equal(t, filepath.Join("/dist", "saturn-linux_arm64.far"), b.GetFarPath())
+}
+
+func TestRequireCleanBuildsTwice(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a project")
+	}
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	assert.Nil(t, err)
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/saturn\n\ngo 1.18\n")
+	writeTestFile(t, filepath.Join(root, "cmd", "saturn", "main.go"), "package main\n\nfunc main() {}\n")
+	writeTestFile(t, filepath.Join(root, configFilename), "process: saturn\noutput: dist\nrequire_clean: true\n")
+	worktree, err := repo.Worktree()
+	assert.Nil(t, err)
+	_, err = worktree.Add(".")
+	assert.Nil(t, err)
+	_, err = worktree.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "fatima", Email: "fatima@example.com", When: time.Now()},
+	})
+	assert.Nil(t, err)
+
+	// the second build sees the far, checksum and index.json of the first one in dist
+	for i := 0; i < 2; i++ {
+		b, err := NewBuilder(Options{WorkDir: root})
+		assert.Nil(t, err)
+		_, err = b.Build()
+		assert.Nil(t, err, "build %d", i+1)
+	}
+	_, err = os.Stat(filepath.Join(root, "dist", IndexFilename))
+	assert.Nil(t, err)
+
+	writeTestFile(t, filepath.Join(root, "notes.txt"), "todo")
+	b, err := NewBuilder(Options{WorkDir: root})
+	assert.Nil(t, err)
+	_, err = b.Build()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,257 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오후 4:10
+ */
+
+package far
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// IndexFilename lists the artifacts of a process directory
+const IndexFilename = "index.json"
+
+// Index is index.json of a process directory
+type Index struct {
+	Process   string       `json:"process"`
+	Artifacts []IndexEntry `json:"artifacts"`
+}
+
+// IndexEntry is a far in the process directory. Path, Checksum and Signature are
+// slash separated paths relative to the directory of index.json
+type IndexEntry struct {
+	Path string `json:"path"`
+	// BuildID is shared by fars of one build, e.g) of several platforms
+	BuildID  string `json:"build_id,omitempty"`
+	Process  string `json:"process"`
+	Version  string `json:"version,omitempty"`
+	Platform string `json:"platform"`
+	Commit   string `json:"commit,omitempty"`
+	// Tag is set when the far was built from a clean tagged commit
+	Tag       string    `json:"tag,omitempty"`
+	BuildTime string    `json:"build_time"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Checksum  string    `json:"checksum"`
+	Signature string    `json:"signature,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// ReadIndex reads index.json in dir. a missing file yields an empty index
+func ReadIndex(dir string) (*Index, error) {
+	index := &Index{Artifacts: make([]IndexEntry, 0)}
+	dat, err := os.ReadFile(filepath.Join(dir, IndexFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(dat, index)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse %s : %w", filepath.Join(dir, IndexFilename), err)
+	}
+	return index, nil
+}
+
+// Write replaces index.json in dir through a temporary file
+func (idx *Index) Write(dir string) error {
+	sort.Slice(idx.Artifacts, func(i, j int) bool {
+		return idx.Artifacts[i].Path < idx.Artifacts[j].Path
+	})
+	dat, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile := filepath.Join(dir, fmt.Sprintf(".%s.%d", IndexFilename, os.Getpid()))
+	err = os.WriteFile(tmpFile, dat, 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpFile, filepath.Join(dir, IndexFilename))
+	if err != nil {
+		os.Remove(tmpFile)
+	}
+	return err
+}
+
+// Find returns the entry of path, nil when absent
+func (idx *Index) Find(path string) *IndexEntry {
+	for i := range idx.Artifacts {
+		if idx.Artifacts[i].Path == path {
+			return &idx.Artifacts[i]
+		}
+	}
+	return nil
+}
+
+// Put adds entry, replacing the one with the same path
+func (idx *Index) Put(entry IndexEntry) {
+	if existing := idx.Find(entry.Path); existing != nil {
+		*existing = entry
+		return
+	}
+	idx.Artifacts = append(idx.Artifacts, entry)
+}
+
+// Remove deletes the entry of path and reports whether it existed
+func (idx *Index) Remove(path string) bool {
+	for i, v := range idx.Artifacts {
+		if v.Path == path {
+			idx.Artifacts = append(idx.Artifacts[:i], idx.Artifacts[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// indexRelPath returns path relative to indexDir in slash form
+func indexRelPath(indexDir, path string) (string, error) {
+	rel, err := filepath.Rel(indexDir, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// updateIndex records the far just built in index.json of the process directory
+func (b *Builder) updateIndex() error {
+	indexDir := b.getIndexDir()
+	index, err := ReadIndex(indexDir)
+	if err != nil {
+		return err
+	}
+	index.Process = b.ExposeProcessName
+
+	entry := IndexEntry{
+		BuildID:   b.getBuildID(),
+		Process:   b.ExposeProcessName,
+		Version:   b.Version,
+		Platform:  b.getPlatform().String(),
+		Commit:    b.buildInfo.Git.CommitHash,
+		BuildTime: b.buildInfo.formatTime(),
+		SHA256:    b.farSHA256,
+		Created:   time.Now().UTC().Truncate(time.Second),
+	}
+	if git := b.buildInfo.Git; len(git.Tag) > 0 && git.TagDistance == 0 && git.StatusKnown && !git.Dirty {
+		entry.Tag = git.Tag
+	}
+	if stat, err := os.Stat(b.farPath); err == nil {
+		entry.Size = stat.Size()
+	}
+	if entry.Path, err = indexRelPath(indexDir, b.farPath); err != nil {
+		return err
+	}
+	if entry.Checksum, err = indexRelPath(indexDir, ChecksumPath(b.farPath)); err != nil {
+		return err
+	}
+	if len(b.signaturePath) > 0 {
+		if entry.Signature, err = indexRelPath(indexDir, b.signaturePath); err != nil {
+			return err
+		}
+	}
+
+	index.Put(entry)
+	err = index.Write(indexDir)
+	if err != nil {
+		return fmt.Errorf("fail to write %s : %w", IndexFilename, err)
+	}
+	return nil
+}
+
+// reserveBuildID picks the id of this build. an earlier build of the same commit within
+// the same second, or a reproducible rebuild, already owns the plain id and a sequence is added
+func (b *Builder) reserveBuildID() error {
+	b.buildID = ""
+	indexDir := b.getIndexDir()
+	index, err := ReadIndex(indexDir)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, v := range index.Artifacts {
+		used[v.BuildID] = true
+	}
+	if len(b.OutputDir) == 0 {
+		if err = EnsureDirectory(indexDir); err != nil {
+			return fmt.Errorf("fail to prepare %s : %w", indexDir, err)
+		}
+	}
+
+	base := b.getBuildID()
+	for seq := 1; ; seq++ {
+		id := base
+		if seq > 1 {
+			id = fmt.Sprintf("%s-%d", base, seq)
+		}
+		if used[id] {
+			continue
+		}
+		if len(b.OutputDir) == 0 {
+			// creating the directory claims the id against a concurrent build
+			err = os.Mkdir(filepath.Join(indexDir, id), 0755)
+			if os.IsExist(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("fail to prepare far dir : %w", err)
+			}
+		}
+		b.buildID = id
+		return nil
+	}
+}
+
+// RemoveArtifact removes the far at path relative to indexDir with its checksum and signature,
+// removes its build directory when it becomes empty and drops it from index.json
+func RemoveArtifact(indexDir, path string) error {
+	farPath, err := safeJoin(indexDir, path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(farPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	os.Remove(ChecksumPath(farPath))
+	os.Remove(SignaturePath(farPath))
+	for dir := filepath.Dir(farPath); dir != filepath.Clean(indexDir); dir = filepath.Dir(dir) {
+		// fails once the directory is not empty
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+
+	index, err := ReadIndex(indexDir)
+	if err != nil {
+		return err
+	}
+	if index.Remove(path) {
+		return index.Write(indexDir)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오후 4:40
+ */
+
+package far
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFarName(t *testing.T) {
+	b := Builder{ExposeProcessName: "saturn", Version: "v1.4.2"}
+	b.buildInfo = buildInfo{
+		Time: time.Date(2026, 10, 18, 16, 40, 0, 0, time.FixedZone("KST", 9*3600)),
+		Git:  GitInfo{Valid: true, CommitHash: "abcdef012345", AbbrevHash: "abcdef0"},
+	}
+	b.setTarget(Platform{OS: "linux", Arch: "arm64"})
+	assert.Equal(t, "saturn-v1.4.2.far", b.farName())
+
+	b.NameTemplate = "{process}_{version}_{platform}_{commit}_{date}"
+	assert.Equal(t, "saturn_v1.4.2_linux_arm64_abcdef0_20261018-074000.far", b.farName())
+
+	b.Version = ""
+	b.NameTemplate = "{process}-{version}-{platform}"
+	assert.Equal(t, "saturn-linux_arm64.far", b.farName())
+
+	assert.Nil(t, checkNameTemplate("{process}-{date}", 1))
+	assert.NotNil(t, checkNameTemplate("{process}-{branch}", 1))
+	assert.NotNil(t, checkNameTemplate("{process}/{version}", 1))
+
+	// fars of several platforms must not share a name
+	assert.NotNil(t, checkNameTemplate("{process}-{version}", 2))
+	assert.Nil(t, checkNameTemplate("{process}-{version}-{platform}", 2))
+	assert.Nil(t, checkNameTemplate("", 2))
+}
+
+func TestUpdateIndex(t *testing.T) {
+	root := t.TempDir()
+	b := Builder{ExposeProcessName: "saturn", OutputRoot: root, Version: "v1.4.2", out: io.Discard}
+	b.buildInfo = buildInfo{
+		Time: time.Date(2026, 10, 18, 16, 40, 0, 0, time.UTC),
+		Git:  GitInfo{Valid: true, StatusKnown: true, CommitHash: "abcdef012345", AbbrevHash: "abcdef0", Tag: "v1.4.2"},
+	}
+	b.setTarget(Platform{OS: "linux", Arch: "amd64"})
+	assert.Equal(t, filepath.Join(root, "saturn", "20261018-164000-abcdef0", "saturn-v1.4.2.far"), b.GetFarPath())
+
+	b.farPath = b.GetFarPath()
+	writeTestFile(t, b.farPath, "far")
+	sum, err := writeChecksum(b.farPath)
+	assert.Nil(t, err)
+	b.farSHA256 = sum
+	assert.Nil(t, b.updateIndex())
+
+	// a later build of the same commit is kept next to the first one
+	b.buildInfo.Time = b.buildInfo.Time.Add(time.Hour)
+	b.buildInfo.Git.Dirty = true
+	b.farPath = b.GetFarPath()
+	writeTestFile(t, b.farPath, "far")
+	assert.Nil(t, b.updateIndex())
+
+	indexDir := filepath.Join(root, "saturn")
+	index, err := ReadIndex(indexDir)
+	assert.Nil(t, err)
+	assert.Equal(t, "saturn", index.Process)
+	assert.Len(t, index.Artifacts, 2)
+	first := index.Artifacts[0]
+	assert.Equal(t, "20261018-164000-abcdef0/saturn-v1.4.2.far", first.Path)
+	assert.Equal(t, "20261018-164000-abcdef0/saturn-v1.4.2.far.sha256", first.Checksum)
+	assert.Equal(t, sum, first.SHA256)
+	assert.Equal(t, "v1.4.2", first.Tag)
+	assert.Equal(t, int64(3), first.Size)
+	assert.Equal(t, "", index.Artifacts[1].Tag, "dirty build is not tagged")
+
+	assert.Nil(t, RemoveArtifact(indexDir, first.Path))
+	_, err = os.Stat(filepath.Join(indexDir, "20261018-164000-abcdef0"))
+	assert.True(t, os.IsNotExist(err), "empty build directory is removed")
+	index, err = ReadIndex(indexDir)
+	assert.Nil(t, err)
+	assert.Len(t, index.Artifacts, 1)
+	assert.Equal(t, "20261018-174000-abcdef0/saturn-v1.4.2.far", index.Artifacts[0].Path)
+
+	assert.NotNil(t, RemoveArtifact(indexDir, "../outside.far"))
+}
+
+func TestMultiPlatformFarNames(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/saturn\n")
+	writeTestFile(t, filepath.Join(root, "cmd", "saturn", "main.go"), "package main\n\nfunc main() {}\n")
+
+	_, err := NewBuilder(Options{WorkDir: root, ProcessName: "saturn", OSArch: "linux_amd64,linux_arm64", NameTemplate: "{process}-{version}"})
+	assert.NotNil(t, err)
+
+	b, err := NewBuilder(Options{WorkDir: root, ProcessName: "saturn", OSArch: "linux_amd64,linux_arm64", NameTemplate: "{process}_{platform}"})
+	assert.Nil(t, err)
+	nameMap := make(map[string]bool)
+	for _, platform := range b.getTargets() {
+		b.setTarget(platform)
+		nameMap[b.farName()] = true
+	}
+	assert.Equal(t, map[string]bool{"saturn_linux_amd64.far": true, "saturn_linux_arm64.far": true}, nameMap)
+}
+
+func TestReserveBuildID(t *testing.T) {
+	root := t.TempDir()
+	b := Builder{ExposeProcessName: "saturn", OutputRoot: root, out: io.Discard}
+	b.buildInfo = buildInfo{
+		Time: time.Date(2026, 10, 18, 16, 40, 0, 0, time.UTC),
+		Git:  GitInfo{Valid: true, AbbrevHash: "abcdef0"},
+	}
+
+	// a reproducible rebuild has the same time and commit
+	idList := make([]string, 0)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, b.reserveBuildID())
+		idList = append(idList, b.getBuildID())
+	}
+	assert.Equal(t, []string{"20261018-164000-abcdef0", "20261018-164000-abcdef0-2", "20261018-164000-abcdef0-3"}, idList)
+	assert.Equal(t, filepath.Join(root, "saturn", "20261018-164000-abcdef0-3"), b.GetFarDir())
+
+	// an id recorded in the index is not reused even without its directory
+	index := &Index{Process: "saturn", Artifacts: []IndexEntry{{Path: "x/saturn.far", BuildID: "20261018-164000-abcdef0-4"}}}
+	assert.Nil(t, index.Write(filepath.Join(root, "saturn")))
+	assert.Nil(t, b.reserveBuildID())
+	assert.Equal(t, "20261018-164000-abcdef0-5", b.getBuildID())
+}
@@ -0,0 +1,186 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오전 9:10
+ */
+
+package far
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Inspection is what a far contains
+type Inspection struct {
+	Path       string       `json:"path"`
+	Size       int64        `json:"size"`
+	SHA256     string       `json:"sha256"`
+	Deployment *Deployment  `json:"deployment"`
+	Files      []FileEntry  `json:"files"`
+	Binaries   []BinaryInfo `json:"binaries"`
+}
+
+// FileEntry is an entry of a far. Type is file, dir or symlink
+type FileEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size uint64 `json:"size"`
+	Mode string `json:"mode"`
+}
+
+// BinaryInfo is the go build information embedded in a binary of a far
+type BinaryInfo struct {
+	Path       string          `json:"path"`
+	GoVersion  string          `json:"go_version"`
+	MainModule string          `json:"main_module,omitempty"`
+	GOOS       string          `json:"goos,omitempty"`
+	GOARCH     string          `json:"goarch,omitempty"`
+	CGO        bool            `json:"cgo"`
+	Deps       []ModuleVersion `json:"deps,omitempty"`
+}
+
+// ModuleVersion is a dependency module compiled into a binary. a replaced module
+// has the version of its replacement
+type ModuleVersion struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+// Platform returns GOOS_GOARCH of the binary
+func (i BinaryInfo) Platform() Platform {
+	return Platform{OS: i.GOOS, Arch: i.GOARCH}
+}
+
+// Inspect opens the far at farPath and collects its deployment, entries and binaries
+func Inspect(farPath string) (*Inspection, error) {
+	farFile, err := Open(farPath)
+	if err != nil {
+		return nil, err
+	}
+	defer farFile.Close()
+
+	inspection := &Inspection{Path: farPath, Files: farFile.Entries(), Binaries: make([]BinaryInfo, 0)}
+	if stat, err := os.Stat(farPath); err == nil {
+		inspection.Size = stat.Size()
+	}
+	inspection.SHA256, err = FileSHA256(farPath)
+	if err != nil {
+		return nil, err
+	}
+	inspection.Deployment, err = farFile.ReadDeployment()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range farFile.Files() {
+		if !isExecutableEntry(file.Name, file.Mode()) {
+			continue
+		}
+		info, err := farFile.ReadBinaryInfo(file.Name)
+		if err != nil {
+			// scripts or non go executables
+			continue
+		}
+		inspection.Binaries = append(inspection.Binaries, *info)
+	}
+	return inspection, nil
+}
+
+func isExecutableEntry(name string, mode os.FileMode) bool {
+	return mode.IsRegular() && (mode&0111 != 0 || strings.HasSuffix(name, ".exe"))
+}
+
+// Entries returns every entry of the far sorted by path. directories are
+// included even when the archive has no entry for them
+func (f *File) Entries() []FileEntry {
+	entryMap := make(map[string]FileEntry)
+	for _, file := range f.Files() {
+		name := strings.TrimSuffix(file.Name, "/")
+		entry := FileEntry{Path: name, Type: "file", Size: file.UncompressedSize64, Mode: formatMode(file.Mode())}
+		switch {
+		case file.FileInfo().IsDir():
+			entry.Type = "dir"
+			entry.Size = 0
+		case file.Mode()&os.ModeSymlink != 0:
+			entry.Type = "symlink"
+		}
+		entryMap[name] = entry
+
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := entryMap[dir]; !ok {
+				entryMap[dir] = FileEntry{Path: dir, Type: "dir", Mode: formatMode(0755)}
+			}
+		}
+	}
+
+	entryList := make([]FileEntry, 0, len(entryMap))
+	for _, v := range entryMap {
+		entryList = append(entryList, v)
+	}
+	sort.Slice(entryList, func(i, j int) bool {
+		return entryList[i].Path < entryList[j].Path
+	})
+	return entryList
+}
+
+// ReadBinaryInfo reads the go build information of the binary name
+func (f *File) ReadBinaryInfo(name string) (*BinaryInfo, error) {
+	dat, err := f.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return readBinaryInfo(name, bytes.NewReader(dat))
+}
+
+func readBinaryInfo(name string, r io.ReaderAt) (*BinaryInfo, error) {
+	bi, err := buildinfo.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &BinaryInfo{Path: name, GoVersion: bi.GoVersion, MainModule: bi.Main.Path}
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "GOOS":
+			info.GOOS = setting.Value
+		case "GOARCH":
+			info.GOARCH = setting.Value
+		case "CGO_ENABLED":
+			info.CGO = setting.Value == "1"
+		}
+	}
+	for _, dep := range bi.Deps {
+		module := ModuleVersion{Path: dep.Path, Version: dep.Version}
+		if dep.Replace != nil {
+			module.Version = dep.Replace.Path
+			if len(dep.Replace.Version) > 0 {
+				module.Version += "@" + dep.Replace.Version
+			}
+		}
+		info.Deps = append(info.Deps, module)
+	}
+	return info, nil
+}
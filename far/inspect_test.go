@@ -0,0 +1,67 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오전 9:30
+ */
+
+package far
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspect(t *testing.T) {
+	source := t.TempDir()
+	// the test binary itself is a go binary carrying build information
+	executable, err := os.Executable()
+	assert.Nil(t, err)
+	assert.Nil(t, CopyFile(executable, filepath.Join(source, "saturn")))
+	assert.Nil(t, os.Chmod(filepath.Join(source, "saturn"), 0755))
+	writeTestFile(t, filepath.Join(source, "bin", "start.sh"), "#!/bin/sh\n")
+	assert.Nil(t, os.Chmod(filepath.Join(source, "bin", "start.sh"), 0755))
+	writeTestFile(t, filepath.Join(source, DeploymentFilename), `{"process":"saturn","process_type":"GENERAL"}`)
+
+	farPath := filepath.Join(t.TempDir(), "saturn.far")
+	assert.Nil(t, Zipit(source, farPath))
+
+	inspection, err := Inspect(farPath)
+	assert.Nil(t, err)
+	assert.Equal(t, "saturn", inspection.Deployment.Process)
+	assert.Len(t, inspection.SHA256, 64)
+
+	pathList := make([]string, 0)
+	for _, v := range inspection.Files {
+		pathList = append(pathList, v.Path)
+	}
+	assert.Equal(t, []string{"bin", "bin/start.sh", DeploymentFilename, "saturn"}, pathList)
+	assert.Equal(t, "dir", inspection.Files[0].Type)
+	assert.Equal(t, "0755", inspection.Files[3].Mode)
+
+	assert.Len(t, inspection.Binaries, 1)
+	assert.Equal(t, "saturn", inspection.Binaries[0].Path)
+	assert.Equal(t, runtime.Version(), inspection.Binaries[0].GoVersion)
+	assert.Equal(t, runtime.GOOS, inspection.Binaries[0].GOOS)
+	assert.Equal(t, runtime.GOARCH, inspection.Binaries[0].GOARCH)
+}
@@ -0,0 +1,288 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오전 11:40
+ */
+
+package far
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// application home layout
+//
+//	<home>/<process>/releases/0001-1.4.1
+//	<home>/<process>/releases/0002-1.4.2
+//	<home>/<process>/current -> releases/0002-1.4.2
+const (
+	releasesDirName = "releases"
+	currentLinkName = "current"
+	// DefaultKeepReleases is the number of previous releases Install keeps
+	DefaultKeepReleases = 3
+)
+
+var releaseNamePattern = regexp.MustCompile(`^(\d+)(-.*)?$`)
+
+// Release is an installed far
+type Release struct {
+	Process string
+	Name    string
+	Dir     string
+}
+
+// InstallOptions configures Install
+type InstallOptions struct {
+	// Home is the application home holding a directory per process
+	Home string
+	// Keep is the number of previous releases kept besides current. every release is kept when negative
+	Keep int
+}
+
+func processHome(home, process string) string {
+	return filepath.Join(home, process)
+}
+
+// Install extracts the far into a new release of its process, switches current to it
+// and removes releases older than the kept ones
+func Install(farPath string, opts InstallOptions) (*Release, error) {
+	farFile, err := Open(farPath)
+	if err != nil {
+		return nil, err
+	}
+	defer farFile.Close()
+
+	deployment, err := farFile.ReadDeployment()
+	if err != nil {
+		return nil, err
+	}
+	process := deployment.Process
+	if len(process) == 0 || process != filepath.Base(process) {
+		return nil, fmt.Errorf("invalid process %q in %s", process, DeploymentFilename)
+	}
+
+	procHome := processHome(opts.Home, process)
+	releasesDir := filepath.Join(procHome, releasesDirName)
+	err = os.MkdirAll(releasesDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkCurrentLink(procHome); err != nil {
+		return nil, err
+	}
+
+	nameList, err := listReleases(releasesDir)
+	if err != nil {
+		return nil, err
+	}
+	seq := 1
+	if len(nameList) > 0 {
+		seq = releaseSeq(nameList[len(nameList)-1]) + 1
+	}
+	release := &Release{Process: process, Name: fmt.Sprintf("%04d%s", seq, releaseLabel(deployment))}
+	release.Dir = filepath.Join(releasesDir, release.Name)
+
+	// a release appears only after it is completely extracted
+	partialDir := filepath.Join(releasesDir, "."+release.Name+".partial")
+	os.RemoveAll(partialDir)
+	err = farFile.Extract(partialDir)
+	if err != nil {
+		os.RemoveAll(partialDir)
+		return nil, err
+	}
+	restoreExecutable(partialDir, process)
+	err = os.Rename(partialDir, release.Dir)
+	if err != nil {
+		os.RemoveAll(partialDir)
+		return nil, err
+	}
+
+	err = switchCurrent(procHome, release.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Keep >= 0 {
+		err = removeOldReleases(releasesDir, release.Name, opts.Keep)
+	}
+	return release, err
+}
+
+// Rollback switches current of process to the release installed before it
+func Rollback(home, process string) (*Release, error) {
+	procHome := processHome(home, process)
+	current, err := CurrentRelease(home, process)
+	if err != nil {
+		return nil, err
+	}
+
+	nameList, err := listReleases(filepath.Join(procHome, releasesDirName))
+	if err != nil {
+		return nil, err
+	}
+	idx := -1
+	for i, v := range nameList {
+		if v == current.Name {
+			idx = i
+		}
+	}
+	if idx <= 0 {
+		return nil, fmt.Errorf("no release before %s of %s", current.Name, process)
+	}
+
+	previous := nameList[idx-1]
+	if err = switchCurrent(procHome, previous); err != nil {
+		return nil, err
+	}
+	return &Release{Process: process, Name: previous, Dir: filepath.Join(procHome, releasesDirName, previous)}, nil
+}
+
+// CurrentRelease returns the release current of process points to
+func CurrentRelease(home, process string) (*Release, error) {
+	procHome := processHome(home, process)
+	link, err := os.Readlink(filepath.Join(procHome, currentLinkName))
+	if err != nil {
+		return nil, fmt.Errorf("no current release of %s : %w", process, err)
+	}
+	name := filepath.Base(link)
+	return &Release{Process: process, Name: name, Dir: filepath.Join(procHome, releasesDirName, name)}, nil
+}
+
+// Releases returns installed releases of process, oldest first
+func Releases(home, process string) ([]Release, error) {
+	releasesDir := filepath.Join(processHome(home, process), releasesDirName)
+	nameList, err := listReleases(releasesDir)
+	if err != nil {
+		return nil, err
+	}
+	releaseList := make([]Release, 0, len(nameList))
+	for _, name := range nameList {
+		releaseList = append(releaseList, Release{Process: process, Name: name, Dir: filepath.Join(releasesDir, name)})
+	}
+	return releaseList, nil
+}
+
+// releaseLabel returns -<version> or -<commit> describing the release
+func releaseLabel(deployment *Deployment) string {
+	label := safeFileName(deployment.Label())
+	if len(label) == 0 {
+		return ""
+	}
+	return "-" + label
+}
+
+func releaseSeq(name string) int {
+	matches := releaseNamePattern.FindStringSubmatch(name)
+	if matches == nil {
+		return 0
+	}
+	seq, _ := strconv.Atoi(matches[1])
+	return seq
+}
+
+// listReleases returns release directory names ordered by sequence
+func listReleases(releasesDir string) ([]string, error) {
+	entryList, err := os.ReadDir(releasesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	nameList := make([]string, 0)
+	for _, entry := range entryList {
+		if entry.IsDir() && releaseNamePattern.MatchString(entry.Name()) {
+			nameList = append(nameList, entry.Name())
+		}
+	}
+	sort.Slice(nameList, func(i, j int) bool {
+		return releaseSeq(nameList[i]) < releaseSeq(nameList[j])
+	})
+	return nameList, nil
+}
+
+// checkCurrentLink refuses a current which is not a symbolic link e.g) a manually copied directory
+func checkCurrentLink(procHome string) error {
+	info, err := os.Lstat(filepath.Join(procHome, currentLinkName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("%s is not a symbolic link", filepath.Join(procHome, currentLinkName))
+	}
+	return nil
+}
+
+// switchCurrent points current to the release by renaming a new link over it, which is atomic
+func switchCurrent(procHome, releaseName string) error {
+	tmpLink := filepath.Join(procHome, fmt.Sprintf(".%s.%d", currentLinkName, os.Getpid()))
+	os.Remove(tmpLink)
+	err := os.Symlink(filepath.Join(releasesDirName, releaseName), tmpLink)
+	if err != nil {
+		return fmt.Errorf("fail to link %s : %w", releaseName, err)
+	}
+	err = os.Rename(tmpLink, filepath.Join(procHome, currentLinkName))
+	if err != nil {
+		os.Remove(tmpLink)
+		return fmt.Errorf("fail to switch current to %s : %w", releaseName, err)
+	}
+	return nil
+}
+
+// removeOldReleases removes releases older than the keep releases before current
+func removeOldReleases(releasesDir, current string, keep int) error {
+	nameList, err := listReleases(releasesDir)
+	if err != nil {
+		return err
+	}
+	for i, name := range nameList {
+		if name != current {
+			continue
+		}
+		for j := 0; j < i-keep; j++ {
+			if err = os.RemoveAll(filepath.Join(releasesDir, nameList[j])); err != nil {
+				return err
+			}
+		}
+		break
+	}
+	return nil
+}
+
+// restoreExecutable makes the process binary and scripts executable even when
+// the far lost permission bits
+func restoreExecutable(dir, process string) {
+	os.Chmod(filepath.Join(dir, process), 0755)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() && isScriptFile(path) {
+			os.Chmod(path, info.Mode().Perm()|0755)
+		}
+		return nil
+	})
+}
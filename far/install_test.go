@@ -0,0 +1,94 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 18. 오후 12:10
+ */
+
+package far
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallAndRollback(t *testing.T) {
+	home := t.TempDir()
+	install := func(version string) *Release {
+		farPath := writeTestFar(t, map[string]string{
+			DeploymentFilename: `{"process":"saturn","version":"` + version + `"}`,
+			"saturn":           "binary " + version,
+			"bin/start.sh":     "#!/bin/sh\n",
+		})
+		release, err := Install(farPath, InstallOptions{Home: home, Keep: 2})
+		assert.Nil(t, err)
+		return release
+	}
+
+	for _, version := range []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"} {
+		install(version)
+	}
+
+	releaseList, err := Releases(home, "saturn")
+	assert.Nil(t, err)
+	nameList := make([]string, 0)
+	for _, v := range releaseList {
+		nameList = append(nameList, v.Name)
+	}
+	assert.Equal(t, []string{"0002-1.1.0", "0003-1.2.0", "0004-1.3.0"}, nameList)
+
+	link, err := os.Readlink(filepath.Join(home, "saturn", "current"))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join("releases", "0004-1.3.0"), link)
+
+	dat, err := os.ReadFile(filepath.Join(home, "saturn", "current", "saturn"))
+	assert.Nil(t, err)
+	assert.Equal(t, "binary 1.3.0", string(dat))
+	for _, name := range []string{"saturn", filepath.Join("bin", "start.sh")} {
+		stat, err := os.Stat(filepath.Join(home, "saturn", "current", name))
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0755), stat.Mode().Perm(), name)
+	}
+
+	release, err := Rollback(home, "saturn")
+	assert.Nil(t, err)
+	assert.Equal(t, "0003-1.2.0", release.Name)
+	current, err := CurrentRelease(home, "saturn")
+	assert.Nil(t, err)
+	assert.Equal(t, "0003-1.2.0", current.Name)
+
+	_, err = Rollback(home, "saturn")
+	assert.Nil(t, err)
+	_, err = Rollback(home, "saturn")
+	assert.NotNil(t, err, "no release before the oldest one")
+
+	release = install("1.4.0")
+	assert.Equal(t, "0005-1.4.0", release.Name)
+}
+
+func TestInstallRefusesDirectoryCurrent(t *testing.T) {
+	home := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(home, "saturn", "current"), 0755))
+	farPath := writeTestFar(t, map[string]string{DeploymentFilename: `{"process":"saturn"}`})
+	_, err := Install(farPath, InstallOptions{Home: home})
+	assert.NotNil(t, err)
+}
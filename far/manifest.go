@@ -0,0 +1,282 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 10:30
+ */
+
+package far
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ManifestFilename = "manifest.json"
+	checksumSuffix   = ".sha256"
+)
+
+// Manifest lists every file of a far with its digest
+type Manifest struct {
+	Algorithm string          `json:"algorithm"`
+	Files     []ManifestEntry `json:"files"`
+}
+
+// ManifestEntry is a file of a far. Mode is the octal permission and Link the target of
+// a symbolic link. SHA256 of a symbolic link is the digest of its target
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Mode   string `json:"mode"`
+	Link   string `json:"link,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+// Find returns the entry of path, nil when absent
+func (m Manifest) Find(path string) *ManifestEntry {
+	for i := range m.Files {
+		if m.Files[i].Path == path {
+			return &m.Files[i]
+		}
+	}
+	return nil
+}
+
+// ManifestError lists far entries which do not match the manifest
+type ManifestError struct {
+	Problems []string
+}
+
+func (e *ManifestError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d entries do not match %s", len(e.Problems), ManifestFilename))
+	for _, v := range e.Problems {
+		sb.WriteString("\n - " + v)
+	}
+	return sb.String()
+}
+
+func formatMode(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", mode.Perm())
+}
+
+// archiveMode returns the permission a file gets in a far
+func (b Builder) archiveMode(mode os.FileMode) os.FileMode {
+	if b.Reproducible {
+		return normalizeMode(mode)
+	}
+	return mode
+}
+
+// createManifest writes manifest.json describing every file in the working dir
+func (b *Builder) createManifest() error {
+	manifest := Manifest{Algorithm: "sha256", Files: make([]ManifestEntry, 0)}
+	err := filepath.Walk(b.workingDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(b.workingDir, path)
+		if err != nil {
+			return err
+		}
+		entry := ManifestEntry{
+			Path: filepath.ToSlash(relPath),
+			Mode: formatMode(b.archiveMode(info.Mode())),
+		}
+
+		var content io.Reader
+		if info.Mode()&os.ModeSymlink != 0 {
+			entry.Link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			content = strings.NewReader(entry.Link)
+		} else {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			content = file
+		}
+		entry.SHA256, entry.Size, err = digest(content)
+		if err != nil {
+			return fmt.Errorf("fail to digest %s : %w", path, err)
+		}
+		manifest.Files = append(manifest.Files, entry)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to create manifest : %w", err)
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+	dat, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("fail to create manifest : %w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(b.workingDir, ManifestFilename), dat, 0644)
+	if err != nil {
+		return fmt.Errorf("fail to write %s : %w", ManifestFilename, err)
+	}
+	return nil
+}
+
+func digest(r io.Reader) (string, int64, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// FileSHA256 returns the hex encoded SHA-256 of the file at path
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	sum, _, err := digest(file)
+	return sum, err
+}
+
+// ChecksumPath returns the checksum file written next to farPath
+func ChecksumPath(farPath string) string {
+	return farPath + checksumSuffix
+}
+
+// writeChecksum writes the SHA-256 of farPath in sha256sum format next to it
+func writeChecksum(farPath string) (string, error) {
+	sum, err := FileSHA256(farPath)
+	if err != nil {
+		return "", fmt.Errorf("fail to digest %s : %w", farPath, err)
+	}
+
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(farPath))
+	err = os.WriteFile(ChecksumPath(farPath), []byte(line), 0644)
+	if err != nil {
+		return "", fmt.Errorf("fail to write checksum : %w", err)
+	}
+	return sum, nil
+}
+
+// ReadChecksum returns the digest recorded in the checksum file of farPath
+func ReadChecksum(farPath string) (string, error) {
+	dat, err := os.ReadFile(ChecksumPath(farPath))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(dat))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file %s", ChecksumPath(farPath))
+	}
+	return fields[0], nil
+}
+
+// ReadManifest returns manifest.json of the far
+func (f *File) ReadManifest() (Manifest, error) {
+	manifest := Manifest{}
+	dat, err := f.ReadFile(ManifestFilename)
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(dat, &manifest)
+	if err != nil {
+		return manifest, fmt.Errorf("fail to parse %s : %s", ManifestFilename, err.Error())
+	}
+	return manifest, nil
+}
+
+// VerifyManifest checks size, mode and SHA-256 of every far entry against manifest.json
+func (f *File) VerifyManifest() error {
+	manifest, err := f.ReadManifest()
+	if err != nil {
+		return err
+	}
+
+	manifestErr := &ManifestError{}
+	seen := make(map[string]bool)
+	for _, file := range f.Files() {
+		if file.FileInfo().IsDir() || file.Name == ManifestFilename {
+			continue
+		}
+		seen[file.Name] = true
+		entry := manifest.Find(file.Name)
+		if entry == nil {
+			manifestErr.Problems = append(manifestErr.Problems, file.Name+" : not listed")
+			continue
+		}
+		if problem := checkManifestEntry(file, *entry); len(problem) > 0 {
+			manifestErr.Problems = append(manifestErr.Problems, file.Name+" : "+problem)
+		}
+	}
+	for _, entry := range manifest.Files {
+		if !seen[entry.Path] {
+			manifestErr.Problems = append(manifestErr.Problems, entry.Path+" : missing")
+		}
+	}
+
+	if len(manifestErr.Problems) > 0 {
+		return manifestErr
+	}
+	return nil
+}
+
+// checkManifestEntry returns the mismatch between file and entry, empty when they match
+func checkManifestEntry(file *zip.File, entry ManifestEntry) string {
+	if perm, err := strconv.ParseUint(entry.Mode, 8, 32); err != nil || os.FileMode(perm) != file.Mode().Perm() {
+		return fmt.Sprintf("mode %s, expected %s", formatMode(file.Mode()), entry.Mode)
+	}
+
+	r, err := file.Open()
+	if err != nil {
+		return err.Error()
+	}
+	defer r.Close()
+	sum, size, err := digest(r)
+	if err != nil {
+		return err.Error()
+	}
+	if size != entry.Size {
+		return fmt.Sprintf("size %d, expected %d", size, entry.Size)
+	}
+	if sum != entry.SHA256 {
+		return fmt.Sprintf("sha256 %s, expected %s", sum, entry.SHA256)
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 10:50
+ */
+
+package far
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifest(t *testing.T) {
+	b := Builder{workingDir: t.TempDir(), out: io.Discard}
+	writeTestFile(t, filepath.Join(b.workingDir, "saturn"), "binary")
+	assert.Nil(t, os.Chmod(filepath.Join(b.workingDir, "saturn"), 0755))
+	writeTestFile(t, filepath.Join(b.workingDir, "conf", "app.yaml"), "port: 8080\n")
+	assert.Nil(t, b.createManifest())
+
+	farPath := filepath.Join(t.TempDir(), "saturn.far")
+	assert.Nil(t, Zipit(b.workingDir, farPath))
+	sum, err := writeChecksum(farPath)
+	assert.Nil(t, err)
+	recorded, err := ReadChecksum(farPath)
+	assert.Nil(t, err)
+	assert.Equal(t, sum, recorded)
+
+	farFile, err := Open(farPath)
+	assert.Nil(t, err)
+	defer farFile.Close()
+	manifest, err := farFile.ReadManifest()
+	assert.Nil(t, err)
+	assert.Len(t, manifest.Files, 2)
+	assert.Equal(t, "conf/app.yaml", manifest.Files[0].Path)
+	assert.Equal(t, "0644", manifest.Files[0].Mode)
+	assert.Equal(t, int64(11), manifest.Files[0].Size)
+	assert.Equal(t, "saturn", manifest.Files[1].Path)
+	assert.Equal(t, "0755", manifest.Files[1].Mode)
+	assert.Len(t, manifest.Files[1].SHA256, 64)
+	assert.Nil(t, farFile.VerifyManifest())
+}
+
+func TestManifestMismatch(t *testing.T) {
+	b := Builder{workingDir: t.TempDir(), out: io.Discard}
+	writeTestFile(t, filepath.Join(b.workingDir, "conf", "app.yaml"), "port: 8080\n")
+	writeTestFile(t, filepath.Join(b.workingDir, "removed.yaml"), "x")
+	assert.Nil(t, b.createManifest())
+	writeTestFile(t, filepath.Join(b.workingDir, "conf", "app.yaml"), "port: 9090\n")
+	writeTestFile(t, filepath.Join(b.workingDir, "added.yaml"), "y")
+	assert.Nil(t, os.Remove(filepath.Join(b.workingDir, "removed.yaml")))
+
+	farPath := filepath.Join(t.TempDir(), "saturn.far")
+	assert.Nil(t, Zipit(b.workingDir, farPath))
+	farFile, err := Open(farPath)
+	assert.Nil(t, err)
+	defer farFile.Close()
+
+	var manifestErr *ManifestError
+	assert.True(t, errors.As(farFile.VerifyManifest(), &manifestErr))
+	assert.Len(t, manifestErr.Problems, 3)
+	assert.Contains(t, manifestErr.Problems[0], "added.yaml : not listed")
+	assert.Contains(t, manifestErr.Problems[1], "conf/app.yaml : sha256")
+	assert.Contains(t, manifestErr.Problems[2], "removed.yaml : missing")
+}
@@ -0,0 +1,170 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 2:10
+ */
+
+package far
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	goModFilename  = "go.mod"
+	goWorkFilename = "go.work"
+)
+
+var errGoModNotFound = fmt.Errorf("not found go.mod")
+
+// FindUpward walks from dir to the filesystem root and returns the first
+// directory containing a regular file named filename
+func FindUpward(dir, filename string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		stat, err := os.Stat(filepath.Join(dir, filename))
+		if err == nil && !stat.IsDir() {
+			return dir, nil
+		}
+
+		parentDir := filepath.Dir(dir)
+		if parentDir == dir {
+			return "", fmt.Errorf("%s not found", filename)
+		}
+		dir = parentDir
+	}
+}
+
+// ReadWorkspaceUses returns absolute module directories declared
+// with `use` directives in the go.work file of workDir
+func ReadWorkspaceUses(workDir string) ([]string, error) {
+	file, err := os.Open(filepath.Join(workDir, goWorkFilename))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	useList := make([]string, 0)
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if inBlock {
+			if line == ")" {
+				inBlock = false
+				continue
+			}
+			useList = append(useList, resolveUsePath(workDir, line))
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if fields[0] != "use" || len(fields) < 2 {
+			continue
+		}
+		if fields[1] == "(" {
+			inBlock = true
+			continue
+		}
+		useList = append(useList, resolveUsePath(workDir, fields[1]))
+	}
+
+	return useList, scanner.Err()
+}
+
+func resolveUsePath(workDir, path string) string {
+	path = strings.Trim(path, "\"`")
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(workDir, path)
+}
+
+// findModuleCandidates returns module root directories visible from baseDir.
+// the nearest go.mod comes first, followed by modules of the enclosing go.work
+func findModuleCandidates(baseDir string) ([]string, error) {
+	candidates := make([]string, 0)
+	appendCandidate := func(dir string) {
+		for _, v := range candidates {
+			if v == dir {
+				return
+			}
+		}
+		candidates = append(candidates, dir)
+	}
+
+	if modDir, err := FindUpward(baseDir, goModFilename); err == nil {
+		appendCandidate(modDir)
+	}
+
+	if workDir, err := FindUpward(baseDir, goWorkFilename); err == nil {
+		useList, err := ReadWorkspaceUses(workDir)
+		if err != nil {
+			return candidates, fmt.Errorf("fail to read %s : %w", filepath.Join(workDir, goWorkFilename), err)
+		}
+		for _, v := range useList {
+			appendCandidate(v)
+		}
+	}
+
+	return candidates, nil
+}
+
+// determineModuleBaseDir picks the module which owns cmd/<procName>.
+// when no module declares it, a module named after the process or the nearest module is used
+func determineModuleBaseDir(baseDir, procName string) (string, error) {
+	candidates, err := findModuleCandidates(baseDir)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", errGoModNotFound
+	}
+
+	for _, dir := range candidates {
+		if CheckDirExist(filepath.Join(dir, cmdDirname, procName)) == nil {
+			return dir, nil
+		}
+	}
+
+	for _, dir := range candidates {
+		if filepath.Base(dir) == procName {
+			return dir, nil
+		}
+	}
+
+	return candidates[0], nil
+}
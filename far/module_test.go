@@ -0,0 +1,64 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 2:40
+ */
+
+package far
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestDetermineModuleBaseDir(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "svc", "go.mod"), "module example.com/svc\n")
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "svc", "cmd", "saturn"), 0755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "svc", "internal", "store"), 0755))
+
+	found, err := determineProjectBaseDir(filepath.Join(root, "svc", "internal", "store"), "saturn")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "svc"), found)
+}
+
+func TestDetermineModuleBaseDirWorkspace(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.work"), "go 1.18\n\nuse (\n\t./lib // shared\n\t./app\n)\n")
+	writeTestFile(t, filepath.Join(root, "lib", "go.mod"), "module example.com/lib\n")
+	writeTestFile(t, filepath.Join(root, "app", "go.mod"), "module example.com/app\n")
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "app", "cmd", "jupiter"), 0755))
+
+	useList, err := ReadWorkspaceUses(root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "lib"), filepath.Join(root, "app")}, useList)
+
+	found, err := determineProjectBaseDir(filepath.Join(root, "lib"), "jupiter")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "app"), found)
+}
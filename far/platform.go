@@ -0,0 +1,108 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 7:20
+ */
+
+package far
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// platformAll selects every platform listed in gofar.yaml
+const platformAll = "all"
+
+// Platform is a GOOS/GOARCH build target
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// ParsePlatform parses a GOOS_GOARCH pair e.g) linux_amd64
+func ParsePlatform(s string) (Platform, error) {
+	tokenList := strings.Split(s, "_")
+	if len(tokenList) != 2 {
+		return Platform{}, fmt.Errorf("invalid os arc (%s)", s)
+	}
+	return Platform{OS: strings.TrimSpace(tokenList[0]), Arch: strings.TrimSpace(tokenList[1])}, nil
+}
+
+// parsePlatformList parses comma separated platforms. "all" expands to configured platforms
+func parsePlatformList(s string, configured []string) ([]Platform, error) {
+	nameList := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if len(v) == 0 {
+			continue
+		}
+		if v == platformAll {
+			if len(configured) == 0 {
+				return nil, fmt.Errorf("no platforms configured in %s", configFilename)
+			}
+			nameList = append(nameList, configured...)
+			continue
+		}
+		nameList = append(nameList, v)
+	}
+
+	platformList := make([]Platform, 0)
+	for _, v := range nameList {
+		platform, err := ParsePlatform(v)
+		if err != nil {
+			return nil, err
+		}
+		if !containsPlatform(platformList, platform) {
+			platformList = append(platformList, platform)
+		}
+	}
+	return platformList, nil
+}
+
+func containsPlatform(list []Platform, p Platform) bool {
+	for _, v := range list {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Platform) String() string {
+	if len(p.OS) == 0 {
+		return ""
+	}
+	return p.OS + "_" + p.Arch
+}
+
+// hostPlatform returns the platform go build targets when GOOS/GOARCH are not given
+func hostPlatform() Platform {
+	p := Platform{OS: os.Getenv("GOOS"), Arch: os.Getenv("GOARCH")}
+	if len(p.OS) == 0 {
+		p.OS = runtime.GOOS
+	}
+	if len(p.Arch) == 0 {
+		p.Arch = runtime.GOARCH
+	}
+	return p
+}
@@ -0,0 +1,48 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 7:40
+ */
+
+package far
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlatformList(t *testing.T) {
+	configured := []string{"linux_amd64", "linux_arm64"}
+
+	list, err := parsePlatformList("all,darwin_arm64,linux_amd64", configured)
+	assert.Nil(t, err)
+	assert.Equal(t, []Platform{{"linux", "amd64"}, {"linux", "arm64"}, {"darwin", "arm64"}}, list)
+
+	list, err = parsePlatformList("", configured)
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+
+	_, err = parsePlatformList("all", nil)
+	assert.NotNil(t, err)
+
+	_, err = parsePlatformList("linux-amd64", nil)
+	assert.NotNil(t, err)
+}
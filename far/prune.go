@@ -0,0 +1,267 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 19. 오전 10:20
+ */
+
+package far
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy decides which builds of a process directory are kept. fars of
+// several platforms made by one build are kept or removed together.
+// a build is kept when it is one of the KeepLast newest, younger than MaxAge
+// or tagged with KeepTagged. when neither KeepLast nor MaxAge is set every build
+// passes that step. afterwards the oldest untagged builds are removed until the
+// total size fits MaxTotalSize. the newest build is never removed
+type RetentionPolicy struct {
+	KeepLast     int
+	KeepTagged   bool
+	MaxAge       time.Duration
+	MaxTotalSize int64
+}
+
+// IsZero reports whether no policy is set, in which case nothing is pruned
+func (p RetentionPolicy) IsZero() bool {
+	return p.KeepLast <= 0 && p.MaxAge <= 0 && p.MaxTotalSize <= 0
+}
+
+// PruneResult lists artifacts kept and removed in a process directory
+type PruneResult struct {
+	Dir     string
+	Kept    []IndexEntry
+	Removed []IndexEntry
+	// Freed is the total size of removed artifacts in bytes
+	Freed int64
+}
+
+// pruneBuild is the fars of one build
+type pruneBuild struct {
+	key     string
+	entries []IndexEntry
+	created time.Time
+	size    int64
+	tagged  bool
+}
+
+// buildKey returns the build id of entry. an entry indexed before build ids were
+// recorded falls back to its build directory, or to its build time and commit
+// when it was written straight into an output directory
+func buildKey(entry IndexEntry) string {
+	if len(entry.BuildID) > 0 {
+		return entry.BuildID
+	}
+	if dir := path.Dir(entry.Path); dir != "." {
+		return dir
+	}
+	return entry.BuildTime + " " + entry.Commit
+}
+
+// groupBuilds groups artifacts by build, newest first
+func groupBuilds(artifacts []IndexEntry) []*pruneBuild {
+	buildMap := make(map[string]*pruneBuild)
+	buildList := make([]*pruneBuild, 0)
+	for _, v := range artifacts {
+		key := buildKey(v)
+		build, ok := buildMap[key]
+		if !ok {
+			build = &pruneBuild{key: key}
+			buildMap[key] = build
+			buildList = append(buildList, build)
+		}
+		build.entries = append(build.entries, v)
+		build.size += v.Size
+		build.tagged = build.tagged || len(v.Tag) > 0
+		if v.Created.After(build.created) {
+			build.created = v.Created
+		}
+	}
+
+	for _, build := range buildList {
+		sort.Slice(build.entries, func(i, j int) bool {
+			return build.entries[i].Path < build.entries[j].Path
+		})
+	}
+	sort.SliceStable(buildList, func(i, j int) bool {
+		if !buildList[i].created.Equal(buildList[j].created) {
+			return buildList[i].created.After(buildList[j].created)
+		}
+		return buildList[i].key > buildList[j].key
+	})
+	return buildList
+}
+
+// PlanPrune splits artifacts into kept and removed ones, both ordered newest build first
+func PlanPrune(artifacts []IndexEntry, policy RetentionPolicy, now time.Time) (kept, removed []IndexEntry) {
+	buildList := groupBuilds(artifacts)
+	kept = make([]IndexEntry, 0)
+	removed = make([]IndexEntry, 0)
+
+	protected := func(i int) bool {
+		return i == 0 || (policy.KeepTagged && buildList[i].tagged)
+	}
+
+	keep := make([]bool, len(buildList))
+	for i, v := range buildList {
+		switch {
+		case policy.IsZero(), protected(i):
+			keep[i] = true
+		case policy.KeepLast <= 0 && policy.MaxAge <= 0:
+			keep[i] = true
+		case policy.KeepLast > 0 && i < policy.KeepLast:
+			keep[i] = true
+		case policy.MaxAge > 0 && now.Sub(v.created) < policy.MaxAge:
+			keep[i] = true
+		}
+	}
+
+	if policy.MaxTotalSize > 0 {
+		var total int64
+		for i, v := range buildList {
+			if keep[i] {
+				total += v.size
+			}
+		}
+		for i := len(buildList) - 1; i >= 0 && total > policy.MaxTotalSize; i-- {
+			if keep[i] && !protected(i) {
+				keep[i] = false
+				total -= buildList[i].size
+			}
+		}
+	}
+
+	for i, v := range buildList {
+		if keep[i] {
+			kept = append(kept, v.entries...)
+		} else {
+			removed = append(removed, v.entries...)
+		}
+	}
+	return kept, removed
+}
+
+// Prune applies policy to the process directory indexDir. with dryRun nothing is removed
+func Prune(indexDir string, policy RetentionPolicy, dryRun bool) (*PruneResult, error) {
+	index, err := ReadIndex(indexDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PruneResult{Dir: indexDir}
+	result.Kept, result.Removed = PlanPrune(index.Artifacts, policy, time.Now())
+	for _, v := range result.Removed {
+		result.Freed += v.Size
+		if dryRun {
+			continue
+		}
+		if err = RemoveArtifact(indexDir, v.Path); err != nil {
+			return result, fmt.Errorf("fail to remove %s : %w", v.Path, err)
+		}
+	}
+	return result, nil
+}
+
+// ProjectRetention returns the retention policy in gofar.yaml of the project of processName
+func ProjectRetention(workDir, processName string) (RetentionPolicy, error) {
+	baseDir, err := determineModuleBaseDir(workDir, processName)
+	if err != nil {
+		return RetentionPolicy{}, nil
+	}
+	config, err := loadProjectConfig(baseDir)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+	return config.Retention.Policy()
+}
+
+// FindIndexDirs returns directories directly under root holding index.json
+func FindIndexDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0)
+	for _, v := range entries {
+		if !v.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, v.Name())
+		if _, err := os.Stat(filepath.Join(dir, IndexFilename)); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// ParseAge parses a go duration with an additional day unit e.g) 72h, 30d
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days := strings.TrimSuffix(s, "d"); days != s {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age : %s", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age : %s", s)
+	}
+	return d, nil
+}
+
+var sizeUnits = []struct {
+	suffix string
+	scale  int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a byte size with binary units e.g) 512M, 2GiB
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	scale := int64(1)
+	number := s
+	for _, v := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(s), strings.ToUpper(v.suffix)) {
+			number = strings.TrimSpace(s[:len(s)-len(v.suffix)])
+			scale = v.scale
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size : %s", s)
+	}
+	return int64(n * float64(scale)), nil
+}
@@ -0,0 +1,194 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 19. 오전 11:05
+ */
+
+package far
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pruneEntries(now time.Time) []IndexEntry {
+	entries := make([]IndexEntry, 0)
+	for i, tag := range []string{"", "v1.0.0", "", "", "v1.1.0", ""} {
+		entries = append(entries, IndexEntry{
+			Path:    filepath.ToSlash(filepath.Join(string(rune('a'+i)), "saturn.far")),
+			Tag:     tag,
+			Size:    100,
+			Created: now.Add(-time.Duration(10-i) * 24 * time.Hour),
+		})
+	}
+	// a..f from oldest (10 days) to newest (5 days)
+	return entries
+}
+
+func paths(entries []IndexEntry) []string {
+	list := make([]string, 0)
+	for _, v := range entries {
+		list = append(list, v.Path)
+	}
+	return list
+}
+
+func TestPlanPrune(t *testing.T) {
+	now := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+	entries := pruneEntries(now)
+
+	kept, removed := PlanPrune(entries, RetentionPolicy{}, now)
+	assert.Len(t, kept, 6)
+	assert.Len(t, removed, 0)
+
+	kept, removed = PlanPrune(entries, RetentionPolicy{KeepLast: 2}, now)
+	assert.Equal(t, []string{"f/saturn.far", "e/saturn.far"}, paths(kept))
+	assert.Equal(t, []string{"d/saturn.far", "c/saturn.far", "b/saturn.far", "a/saturn.far"}, paths(removed))
+
+	kept, _ = PlanPrune(entries, RetentionPolicy{KeepLast: 2, KeepTagged: true}, now)
+	assert.Equal(t, []string{"f/saturn.far", "e/saturn.far", "b/saturn.far"}, paths(kept))
+
+	// keep last or younger than max age
+	kept, _ = PlanPrune(entries, RetentionPolicy{KeepLast: 1, MaxAge: 7*24*time.Hour + time.Minute}, now)
+	assert.Equal(t, []string{"f/saturn.far", "e/saturn.far", "d/saturn.far"}, paths(kept))
+
+	// the newest far is kept even when it is too old
+	kept, _ = PlanPrune(entries, RetentionPolicy{MaxAge: time.Hour}, now)
+	assert.Equal(t, []string{"f/saturn.far"}, paths(kept))
+
+	kept, removed = PlanPrune(entries, RetentionPolicy{MaxTotalSize: 350, KeepTagged: true}, now)
+	assert.Equal(t, []string{"f/saturn.far", "e/saturn.far", "b/saturn.far"}, paths(kept))
+	assert.Equal(t, []string{"d/saturn.far", "c/saturn.far", "a/saturn.far"}, paths(removed))
+}
+
+func TestPlanPruneMultiPlatform(t *testing.T) {
+	now := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+	entries := make([]IndexEntry, 0)
+	for i, build := range []string{"20261017-100000-aaaaaaa", "20261018-100000-bbbbbbb"} {
+		for j, platform := range []string{"linux_amd64", "linux_arm64"} {
+			entries = append(entries, IndexEntry{
+				Path:     build + "/saturn-" + platform + ".far",
+				Platform: platform,
+				Size:     100,
+				// fars of one build are written one after another
+				Created: now.Add(-time.Duration(2-i)*24*time.Hour + time.Duration(j)*time.Second),
+			})
+		}
+	}
+
+	kept, removed := PlanPrune(entries, RetentionPolicy{KeepLast: 1}, now)
+	assert.Equal(t, []string{
+		"20261018-100000-bbbbbbb/saturn-linux_amd64.far",
+		"20261018-100000-bbbbbbb/saturn-linux_arm64.far",
+	}, paths(kept))
+	assert.Len(t, removed, 2)
+
+	// the newest build is kept whole even beyond the size
+	kept, _ = PlanPrune(entries, RetentionPolicy{MaxTotalSize: 100}, now)
+	assert.Len(t, kept, 2)
+
+	// fars written straight into an output directory are grouped by build time and commit
+	flat := []IndexEntry{
+		{Path: "saturn-linux_amd64.far", BuildTime: "2026-10-18 10:00:00 UTC", Commit: "bbbb", Created: now},
+		{Path: "saturn-linux_arm64.far", BuildTime: "2026-10-18 10:00:00 UTC", Commit: "bbbb", Created: now.Add(time.Second)},
+	}
+	kept, _ = PlanPrune(flat, RetentionPolicy{KeepLast: 1}, now)
+	assert.Len(t, kept, 2)
+}
+
+func TestPlanPruneSameCommit(t *testing.T) {
+	now := time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC)
+	// two reproducible builds of one commit written into one output directory
+	entries := []IndexEntry{
+		{Path: "saturn-linux_amd64.far", BuildID: "20261018-100000-bbbbbbb", BuildTime: "2026-10-18 10:00:00 UTC", Commit: "bbbb", Size: 100, Created: now.Add(-time.Hour)},
+		{Path: "saturn-linux_arm64.far", BuildID: "20261018-100000-bbbbbbb", BuildTime: "2026-10-18 10:00:00 UTC", Commit: "bbbb", Size: 100, Created: now.Add(-time.Hour)},
+		{Path: "20261018-100000-bbbbbbb-2/saturn.far", BuildID: "20261018-100000-bbbbbbb-2", BuildTime: "2026-10-18 10:00:00 UTC", Commit: "bbbb", Size: 100, Created: now},
+	}
+
+	kept, removed := PlanPrune(entries, RetentionPolicy{KeepLast: 1}, now)
+	assert.Equal(t, []string{"20261018-100000-bbbbbbb-2/saturn.far"}, paths(kept))
+	assert.Equal(t, []string{"saturn-linux_amd64.far", "saturn-linux_arm64.far"}, paths(removed))
+
+	kept, _ = PlanPrune(entries, RetentionPolicy{KeepLast: 2}, now)
+	assert.Len(t, kept, 3)
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now().UTC()
+	index := &Index{Process: "saturn", Artifacts: pruneEntries(now)}
+	for _, v := range index.Artifacts {
+		writeTestFile(t, filepath.Join(dir, filepath.FromSlash(v.Path)), "far")
+	}
+	assert.Nil(t, index.Write(dir))
+
+	policy := RetentionPolicy{KeepLast: 3}
+	result, err := Prune(dir, policy, true)
+	assert.Nil(t, err)
+	assert.Len(t, result.Removed, 3)
+	assert.Equal(t, int64(300), result.Freed)
+	_, err = os.Stat(filepath.Join(dir, "a", "saturn.far"))
+	assert.Nil(t, err, "dry run removes nothing")
+
+	result, err = Prune(dir, policy, false)
+	assert.Nil(t, err)
+	assert.Len(t, result.Removed, 3)
+	_, err = os.Stat(filepath.Join(dir, "a"))
+	assert.True(t, os.IsNotExist(err))
+
+	index, err = ReadIndex(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"d/saturn.far", "e/saturn.far", "f/saturn.far"}, paths(index.Artifacts))
+
+	dirs, err := FindIndexDirs(filepath.Dir(dir))
+	assert.Nil(t, err)
+	assert.Contains(t, dirs, dir)
+}
+
+func TestParseRetention(t *testing.T) {
+	age, err := ParseAge("30d")
+	assert.Nil(t, err)
+	assert.Equal(t, 30*24*time.Hour, age)
+	age, err = ParseAge("36h")
+	assert.Nil(t, err)
+	assert.Equal(t, 36*time.Hour, age)
+	_, err = ParseAge("-1d")
+	assert.NotNil(t, err)
+
+	size, err := ParseSize("2GiB")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2<<30), size)
+	size, err = ParseSize("512M")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(512<<20), size)
+	size, err = ParseSize("1000")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000), size)
+	_, err = ParseSize("big")
+	assert.NotNil(t, err)
+
+	policy, err := RetentionConfig{KeepLast: 5, MaxAge: "7d", MaxTotalSize: "1G"}.Policy()
+	assert.Nil(t, err)
+	assert.Equal(t, RetentionPolicy{KeepLast: 5, MaxAge: 7 * 24 * time.Hour, MaxTotalSize: 1 << 30}, policy)
+}
@@ -0,0 +1,296 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 19. 오후 2:10
+ */
+
+package far
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrPublish reports a far which could not be uploaded to the artifact repository
+var ErrPublish = errors.New("publish failed")
+
+const (
+	DefaultPublishRetries   = 3
+	defaultPublishRetryWait = time.Second
+	// defaultPublishTimeout bounds a single request of the default client
+	defaultPublishTimeout = 5 * time.Minute
+)
+
+// PublishOptions describes a raw artifact repository (nexus raw, artifactory generic, ...)
+// receiving fars at <URL>/<process>/<version>/<far name>
+type PublishOptions struct {
+	URL string
+	// Method is PUT (default) or POST
+	Method   string
+	Username string
+	Password string
+	// Token is sent as a bearer token instead of basic auth
+	Token string
+	// Retries is the number of retries of a request failing with a network error or 5xx
+	Retries   int
+	RetryWait time.Duration
+	// Force uploads a far over an existing one with a different digest
+	Force bool
+	// Client defaults to a client giving up a request after 5 minutes
+	Client *http.Client
+}
+
+// PublishResult reports the remote location of a far
+type PublishResult struct {
+	URL    string
+	SHA256 string
+	// Skipped reports the repository already held the same far
+	Skipped  bool
+	Uploaded []string
+}
+
+// Publish uploads the far with its checksum and signature. the checksum is uploaded last
+// and a remote checksum equal to the local digest means the far is already published.
+// a local checksum file not matching the far fails the publish
+func Publish(farPath string, opts PublishOptions) (*PublishResult, error) {
+	if len(opts.URL) == 0 {
+		return nil, fmt.Errorf("%w : repository url is required", ErrPublish)
+	}
+	if len(opts.Method) == 0 {
+		opts.Method = http.MethodPut
+	}
+	opts.Method = strings.ToUpper(opts.Method)
+	if opts.Method != http.MethodPut && opts.Method != http.MethodPost {
+		return nil, fmt.Errorf("%w : unsupported method %s", ErrPublish, opts.Method)
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: defaultPublishTimeout}
+	}
+	if opts.RetryWait <= 0 {
+		opts.RetryWait = defaultPublishRetryWait
+	}
+
+	remoteDir, err := publishDir(farPath)
+	if err != nil {
+		return nil, err
+	}
+	farURL, err := joinURL(opts.URL, remoteDir, filepath.Base(farPath))
+	if err != nil {
+		return nil, fmt.Errorf("%w : %s", ErrPublish, err.Error())
+	}
+
+	sum, err := FileSHA256(farPath)
+	if err != nil {
+		return nil, err
+	}
+	result := &PublishResult{URL: farURL, SHA256: sum, Uploaded: make([]string, 0)}
+	checksumPath := ChecksumPath(farPath)
+	if _, err = os.Stat(checksumPath); os.IsNotExist(err) {
+		if _, err = writeChecksum(farPath); err != nil {
+			return nil, err
+		}
+	} else {
+		localSum, err := ReadChecksum(farPath)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read checksum : %w", err)
+		}
+		if !strings.EqualFold(localSum, sum) {
+			return nil, fmt.Errorf("%w : %s records sha256 %s but the far has %s", ErrPublish, checksumPath, localSum, sum)
+		}
+	}
+
+	remoteSum, err := opts.fetchChecksum(ChecksumPath(farURL))
+	if err != nil {
+		return nil, err
+	}
+	if len(remoteSum) > 0 {
+		if remoteSum == sum {
+			result.Skipped = true
+			return result, nil
+		}
+		if !opts.Force {
+			return nil, fmt.Errorf("%w : %s already exists with sha256 %s", ErrPublish, farURL, remoteSum)
+		}
+	}
+
+	files := []string{farPath}
+	if _, err = os.Stat(SignaturePath(farPath)); err == nil {
+		files = append(files, SignaturePath(farPath))
+	}
+	files = append(files, checksumPath)
+	for _, file := range files {
+		target := farURL + strings.TrimPrefix(file, farPath)
+		if err = opts.upload(file, target); err != nil {
+			return result, err
+		}
+		result.Uploaded = append(result.Uploaded, target)
+	}
+	return result, nil
+}
+
+// ProjectPublish returns the publish config in gofar.yaml of the project of processName
+func ProjectPublish(workDir, processName string) (PublishConfig, error) {
+	baseDir, err := determineModuleBaseDir(workDir, processName)
+	if err != nil {
+		return PublishConfig{}, nil
+	}
+	config, err := loadProjectConfig(baseDir)
+	if err != nil {
+		return PublishConfig{}, err
+	}
+	return config.Publish, nil
+}
+
+// publishDir returns <process>/<version> of the far. a far without version uses its digest
+func publishDir(farPath string) (string, error) {
+	farFile, err := Open(farPath)
+	if err != nil {
+		return "", err
+	}
+	defer farFile.Close()
+
+	deployment, err := farFile.ReadDeployment()
+	if err != nil {
+		return "", err
+	}
+	label := deployment.Label()
+	if len(label) == 0 {
+		return "", fmt.Errorf("%w : %s has neither version nor commit", ErrPublish, farPath)
+	}
+	return path.Join(safeFileName(deployment.Process), safeFileName(label)), nil
+}
+
+func joinURL(base string, elem ...string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid repository url : %s", base)
+	}
+	u.Path = path.Join(append([]string{"/", u.Path}, elem...)...)
+	return u.String(), nil
+}
+
+// fetchChecksum returns the remote digest, empty when the checksum does not exist
+func (o PublishOptions) fetchChecksum(target string) (string, error) {
+	var sum string
+	err := o.retry(func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, target, nil)
+	}, func(resp *http.Response) error {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		dat, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(string(dat))
+		if len(fields) == 0 {
+			return fmt.Errorf("%w : empty checksum %s", ErrPublish, target)
+		}
+		sum = strings.ToLower(fields[0])
+		return nil
+	})
+	return sum, err
+}
+
+func (o PublishOptions) upload(file, target string) error {
+	return o.retry(func() (*http.Request, error) {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		req, err := http.NewRequest(o.Method, target, f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		req.ContentLength = stat.Size()
+		req.Header.Set("Content-Type", "application/octet-stream")
+		return req, nil
+	}, nil)
+}
+
+// retry sends the request built by newRequest, retrying network errors, 429 and 5xx.
+// handle reads a successful or 404 response. without handle 404 is a failure
+func (o PublishOptions) retry(newRequest func() (*http.Request, error), handle func(resp *http.Response) error) error {
+	var lastErr error
+	wait := o.RetryWait
+	for attempt := 0; attempt <= o.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
+		o.authorize(req)
+		resp, err := o.Client.Do(req)
+		if err != nil {
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			lastErr = fmt.Errorf("%w : %s %s : %s", ErrPublish, req.Method, req.URL, err.Error())
+			continue
+		}
+
+		status := resp.StatusCode
+		switch {
+		case status >= 200 && status < 300, status == http.StatusNotFound && handle != nil:
+			if handle != nil {
+				err = handle(resp)
+			}
+			resp.Body.Close()
+			return err
+		case status == http.StatusTooManyRequests || status >= 500:
+			lastErr = fmt.Errorf("%w : %s %s : %s", ErrPublish, req.Method, req.URL, resp.Status)
+			resp.Body.Close()
+		default:
+			resp.Body.Close()
+			return fmt.Errorf("%w : %s %s : %s", ErrPublish, req.Method, req.URL, resp.Status)
+		}
+	}
+	return lastErr
+}
+
+func (o PublishOptions) authorize(req *http.Request) {
+	if len(o.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+o.Token)
+	} else if len(o.Username) > 0 {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+}
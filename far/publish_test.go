@@ -0,0 +1,176 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 19. 오후 3:00
+ */
+
+package far
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rawRepository imitates a raw repository storing uploaded bodies by path
+type rawRepository struct {
+	sync.Mutex
+	files    map[string][]byte
+	requests []string
+	// failures is the number of uploads answered with 503 before accepting
+	failures int
+}
+
+func (r *rawRepository) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+
+	if user, password, ok := req.BasicAuth(); !ok || user != "deployer" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		dat, ok := r.files[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(dat)
+	case http.MethodPut:
+		if r.failures > 0 {
+			r.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		dat, _ := io.ReadAll(req.Body)
+		r.files[req.URL.Path] = dat
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	repo := &rawRepository{files: make(map[string][]byte), failures: 1}
+	server := httptest.NewServer(repo)
+	defer server.Close()
+
+	farPath := writeTestFar(t, map[string]string{
+		DeploymentFilename: `{"process":"saturn","version":"v1.4.2"}`,
+		"saturn":           "binary",
+	})
+	writeTestFile(t, SignaturePath(farPath), "signature\n")
+	opts := PublishOptions{
+		URL:       server.URL + "/repository/far-raw/",
+		Username:  "deployer",
+		Password:  "secret",
+		Retries:   2,
+		RetryWait: time.Millisecond,
+	}
+
+	result, err := Publish(farPath, opts)
+	assert.Nil(t, err)
+	assert.False(t, result.Skipped)
+	assert.Equal(t, server.URL+"/repository/far-raw/saturn/v1.4.2/saturn.far", result.URL)
+	assert.Equal(t, []string{result.URL, result.URL + ".sig", result.URL + ".sha256"}, result.Uploaded)
+
+	local, _ := os.ReadFile(farPath)
+	assert.Equal(t, local, repo.files["/repository/far-raw/saturn/v1.4.2/saturn.far"])
+	checksum, _ := os.ReadFile(ChecksumPath(farPath))
+	assert.Equal(t, checksum, repo.files["/repository/far-raw/saturn/v1.4.2/saturn.far.sha256"])
+	assert.Equal(t, []string{
+		"GET /repository/far-raw/saturn/v1.4.2/saturn.far.sha256",
+		"PUT /repository/far-raw/saturn/v1.4.2/saturn.far",
+		"PUT /repository/far-raw/saturn/v1.4.2/saturn.far",
+		"PUT /repository/far-raw/saturn/v1.4.2/saturn.far.sig",
+		"PUT /repository/far-raw/saturn/v1.4.2/saturn.far.sha256",
+	}, repo.requests)
+
+	// the same far again is detected by its digest
+	repo.requests = nil
+	result, err = Publish(farPath, opts)
+	assert.Nil(t, err)
+	assert.True(t, result.Skipped)
+	assert.Len(t, repo.requests, 1)
+
+	// a different far of the same version is refused unless forced
+	other := writeTestFar(t, map[string]string{
+		DeploymentFilename: `{"process":"saturn","version":"v1.4.2"}`,
+		"saturn":           "rebuilt binary",
+	})
+	_, err = Publish(other, opts)
+	assert.True(t, errors.Is(err, ErrPublish))
+	opts.Force = true
+	result, err = Publish(other, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{result.URL, result.URL + ".sha256"}, result.Uploaded)
+
+	opts.Password = "wrong"
+	_, err = Publish(farPath, opts)
+	assert.True(t, errors.Is(err, ErrPublish))
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestPublishStaleChecksum(t *testing.T) {
+	repo := &rawRepository{files: make(map[string][]byte)}
+	server := httptest.NewServer(repo)
+	defer server.Close()
+
+	farPath := writeTestFar(t, map[string]string{DeploymentFilename: `{"process":"saturn","version":"v1.4.2"}`})
+	writeTestFile(t, ChecksumPath(farPath), strings.Repeat("0", 64)+"  saturn.far\n")
+
+	_, err := Publish(farPath, PublishOptions{URL: server.URL})
+	assert.True(t, errors.Is(err, ErrPublish))
+	assert.Contains(t, err.Error(), "records sha256")
+	assert.Empty(t, repo.files)
+}
+
+func TestPublishRetryExhausted(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if req.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	farPath := writeTestFar(t, map[string]string{DeploymentFilename: `{"process":"saturn","version":"v1.4.2"}`})
+	_, err := Publish(farPath, PublishOptions{URL: server.URL, Token: "token", Retries: 2, RetryWait: time.Millisecond})
+	assert.True(t, errors.Is(err, ErrPublish))
+	assert.Contains(t, err.Error(), "502")
+	assert.Equal(t, 3, calls)
+
+	_, err = Publish(farPath, PublishOptions{URL: server.URL, Method: "PATCH"})
+	assert.NotNil(t, err)
+}
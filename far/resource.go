@@ -0,0 +1,550 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 8:40
+ */
+
+package far
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+const farignoreFilename = ".farignore"
+
+// prepare resource...
+func (b *Builder) prepareResource() error {
+	err := b.loadResourceFiles()
+	if err != nil {
+		return err
+	}
+
+	// determine proc type
+	uiProcXml := filepath.Join(b.workingDir, fmt.Sprintf("%s.ui.xml", b.ExposeProcessName))
+	err = CheckFileExist(uiProcXml)
+	if err == nil {
+		// exist ui xml
+		b.procType = procTypeUI
+	}
+
+	return nil
+}
+
+func (b *Builder) loadResourceFiles() error {
+	if len(b.ResourceDir) == 0 {
+		return b.loadResourceFromProject()
+	}
+	return b.loadResourceFromDesginatedDir()
+}
+
+func (b *Builder) loadResourceFromDesginatedDir() error {
+	fmt.Fprintf(b.out, "\n>> copying resources...\n")
+	rule, err := b.newResourceRule(b.ResourceDir, false)
+	if err != nil {
+		return err
+	}
+	// unlike the project scan, dotfiles of the resource directory are resources too
+	rule.dotfiles = true
+
+	decisionList, err := scanResources(b.ResourceDir, "", rule)
+	if err != nil {
+		return err
+	}
+	b.printDecisions(decisionList)
+	if err = checkResourceCollision(decisionList, false, b.reservedFarNames()); err != nil {
+		return err
+	}
+
+	count := 0
+	copyErr := &CopyError{}
+	for _, decision := range decisionList {
+		if !decision.Selected {
+			continue
+		}
+		source := filepath.Join(b.ResourceDir, decision.RelPath)
+		targetFile := filepath.Join(b.workingDir, decision.RelPath)
+		err = EnsureDirectory(filepath.Dir(targetFile))
+		if err == nil {
+			err = copyResource(source, targetFile, b.SymlinkPolicy)
+		}
+		if err != nil {
+			copyErr.Failures = append(copyErr.Failures, FileFailure{Path: source, Err: err})
+			continue
+		}
+		count++
+	}
+	if len(copyErr.Failures) > 0 {
+		return copyErr
+	}
+
+	fmt.Fprintf(b.out, "resources directory copied... (%d files)\n", count)
+	return nil
+}
+
+var includeSuffixList = [...]string{"properties", "xml", "json", "yaml", "sh", "yml", "dat", "p8", "rb", "rbw", "lua"}
+
+// resourceRule selects resource files. rules are applied in order :
+// .farignore, exclude globs, include globs, then includeSuffixList when defaultSuffix is set.
+// a glob is matched against the file name and the slash separated path relative to the scan root
+type resourceRule struct {
+	Include       []string
+	Exclude       []string
+	defaultSuffix bool
+	// dotfiles selects names starting with a dot. .farignore itself is never selected
+	dotfiles bool
+	// symlinks decides whether a link to a directory is walked
+	symlinks SymlinkPolicy
+	ignore   farIgnore
+	// prefix is the project relative path of the scan root. .farignore patterns are matched with it
+	prefix []string
+	// artifactDirs are the output directories under the scan root, never packaged
+	artifactDirs []string
+}
+
+// newResourceRule loads .farignore of the project and of rootDir when it differs
+func (b *Builder) newResourceRule(rootDir string, defaultSuffix bool) (resourceRule, error) {
+	rule := resourceRule{
+		Include:       b.ResourceInclude,
+		Exclude:       b.ResourceExclude,
+		defaultSuffix: defaultSuffix,
+		symlinks:      b.SymlinkPolicy,
+	}
+
+	ignore, err := readFarIgnore(b.ProjectBaseDir, nil)
+	if err != nil {
+		return rule, err
+	}
+
+	relRoot, err := filepath.Rel(b.ProjectBaseDir, rootDir)
+	if err != nil || relRoot == ".." || strings.HasPrefix(relRoot, ".."+string(os.PathSeparator)) {
+		// scan root is outside of project. only its own .farignore applies
+		ignore = nil
+		relRoot = "."
+	}
+	if relRoot != "." {
+		rule.prefix = splitPath(relRoot)
+	}
+	if filepath.Clean(rootDir) != filepath.Clean(b.ProjectBaseDir) {
+		rootIgnore, err := readFarIgnore(rootDir, rule.prefix)
+		if err != nil {
+			return rule, err
+		}
+		ignore = append(ignore, rootIgnore...)
+	}
+	rule.ignore = ignore
+	rule.artifactDirs = b.artifactDirsUnder(rootDir)
+	return rule, nil
+}
+
+// artifactDirsUnder returns the output dir and output root relative to rootDir when they are inside it
+func (b *Builder) artifactDirsUnder(rootDir string) []string {
+	rootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil
+	}
+	dirList := make([]string, 0)
+	for _, dir := range []string{b.OutputDir, b.getOutputRoot()} {
+		if len(dir) == 0 {
+			continue
+		}
+		dir, err = filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(rootDir, dir)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			continue
+		}
+		dirList = append(dirList, rel)
+	}
+	return dirList
+}
+
+// decide reports whether relPath is selected and describes the rule deciding it
+func (r resourceRule) decide(relPath string, isDir bool) (bool, string) {
+	for _, dir := range r.artifactDirs {
+		if relPath == dir || strings.HasPrefix(relPath, dir+string(os.PathSeparator)) {
+			return false, "artifact dir " + filepath.ToSlash(dir)
+		}
+	}
+
+	path := append(append([]string{}, r.prefix...), splitPath(relPath)...)
+	if result, ignoreRule := r.ignore.match(path, isDir); result == gitignore.Exclude {
+		return false, ignoreRule.String()
+	}
+
+	if pattern, ok := matchAnyPattern(r.Exclude, relPath); ok {
+		return false, "exclude " + pattern
+	}
+
+	if isDir {
+		return true, ""
+	}
+
+	if len(r.Include) > 0 {
+		if pattern, ok := matchAnyPattern(r.Include, relPath); ok {
+			return true, "include " + pattern
+		}
+		return false, "not included"
+	}
+
+	if !r.defaultSuffix {
+		return true, "resource dir"
+	}
+	name := filepath.Base(relPath)
+	for _, s := range includeSuffixList {
+		if strings.HasSuffix(name, s) {
+			return true, "suffix " + s
+		}
+	}
+	return false, "not included"
+}
+
+func matchAnyPattern(patternList []string, relPath string) (string, bool) {
+	relPath = filepath.ToSlash(relPath)
+	name := filepath.Base(relPath)
+	for _, pattern := range patternList {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return pattern, true
+		}
+		if ok, _ := filepath.Match(pattern, relPath); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func splitPath(relPath string) []string {
+	return strings.Split(filepath.ToSlash(filepath.Clean(relPath)), "/")
+}
+
+// ignoreRule is a pattern of a .farignore file
+type ignoreRule struct {
+	file    string
+	line    int
+	text    string
+	pattern gitignore.Pattern
+}
+
+func (r ignoreRule) String() string {
+	return fmt.Sprintf("%s:%d %s", r.file, r.line, r.text)
+}
+
+// farIgnore holds .farignore patterns in gitignore syntax. the last matching pattern wins
+type farIgnore []ignoreRule
+
+// readFarIgnore parses dir/.farignore. domain is the project relative path of dir
+func readFarIgnore(dir string, domain []string) (farIgnore, error) {
+	ignoreFile := filepath.Join(dir, farignoreFilename)
+	file, err := os.Open(ignoreFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fail to read %s : %w", ignoreFile, err)
+	}
+	defer file.Close()
+
+	displayName := farignoreFilename
+	if len(domain) > 0 {
+		displayName = strings.Join(append(append([]string{}, domain...), farignoreFilename), "/")
+	}
+
+	ignore := make(farIgnore, 0)
+	lineNo := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ignore = append(ignore, ignoreRule{
+			file:    displayName,
+			line:    lineNo,
+			text:    line,
+			pattern: gitignore.ParsePattern(line, domain),
+		})
+	}
+	return ignore, scanner.Err()
+}
+
+func (f farIgnore) match(path []string, isDir bool) (gitignore.MatchResult, *ignoreRule) {
+	for i := len(f) - 1; i >= 0; i-- {
+		if result := f[i].pattern.Match(path, isDir); result != gitignore.NoMatch {
+			return result, &f[i]
+		}
+	}
+	return gitignore.NoMatch, nil
+}
+
+// resourceDecision records why a file or directory was selected or skipped
+type resourceDecision struct {
+	RelPath  string
+	IsDir    bool
+	Selected bool
+	Rule     string
+}
+
+func (b *Builder) printDecisions(decisionList []resourceDecision) {
+	if !b.Verbose {
+		return
+	}
+	for _, v := range decisionList {
+		if v.IsDir && v.Selected {
+			continue
+		}
+		mark := "+"
+		if !v.Selected {
+			mark = "-"
+		}
+		name := filepath.ToSlash(v.RelPath)
+		if v.IsDir {
+			name += "/"
+		}
+		fmt.Fprintf(b.out, "  %s %s (%s)\n", mark, name, v.Rule)
+	}
+}
+
+// printResourcePlan lists the decision of every resource file in verbose mode
+func (b *Builder) printResourcePlan() {
+	if !b.Verbose {
+		return
+	}
+
+	rootDir, defaultSuffix := b.ResourceDir, false
+	if len(rootDir) == 0 {
+		rootDir, defaultSuffix = b.ProjectBaseDir, true
+	}
+	rule, err := b.newResourceRule(rootDir, defaultSuffix)
+	if err != nil {
+		fmt.Fprintf(b.out, "   %s\n", err.Error())
+		return
+	}
+	decisionList, err := scanResources(rootDir, "", rule)
+	if err != nil {
+		fmt.Fprintf(b.out, "   %s\n", err.Error())
+		return
+	}
+	b.printDecisions(decisionList)
+}
+
+func (b *Builder) loadResourceFromProject() error {
+	rule, err := b.newResourceRule(b.ProjectBaseDir, true)
+	if err != nil {
+		return err
+	}
+
+	decisionList, err := scanResources(b.ProjectBaseDir, "", rule)
+	if err != nil {
+		return err
+	}
+	b.printDecisions(decisionList)
+
+	if err = checkResourceCollision(decisionList, !b.PreserveResourcePaths, b.reservedFarNames()); err != nil {
+		return err
+	}
+
+	count := 0
+	copyErr := &CopyError{}
+	for _, decision := range decisionList {
+		if !decision.Selected || decision.IsDir {
+			continue
+		}
+		resourceFilePath := filepath.Join(b.ProjectBaseDir, decision.RelPath)
+		targetFile := filepath.Join(b.workingDir, filepath.Base(resourceFilePath))
+		if b.PreserveResourcePaths {
+			targetFile = filepath.Join(b.workingDir, decision.RelPath)
+		}
+		fmt.Fprintf(b.out, "copy : %s\n", resourceFilePath)
+		copyFailure := EnsureDirectory(filepath.Dir(targetFile))
+		if copyFailure == nil {
+			copyFailure = copyResource(resourceFilePath, targetFile, b.SymlinkPolicy)
+		}
+		if copyFailure != nil {
+			copyErr.Failures = append(copyErr.Failures, FileFailure{Path: resourceFilePath, Err: copyFailure})
+			continue
+		}
+		if isScriptFile(targetFile) {
+			os.Chmod(targetFile, 0755)
+		}
+		count++
+	}
+
+	if len(copyErr.Failures) > 0 {
+		return copyErr
+	}
+
+	fmt.Fprintf(b.out, "total %d resource files copied...\n", count)
+	return nil
+}
+
+// scanResources walks baseDir/relDir and returns a decision for every file
+// and every skipped directory. selected files have IsDir false and Selected true
+func scanResources(baseDir, relDir string, rule resourceRule) ([]resourceDecision, error) {
+	scanner := resourceScanner{baseDir: baseDir, rule: rule, visited: make(map[string]bool)}
+	if realDir, err := filepath.EvalSymlinks(baseDir); err == nil {
+		scanner.visited[realDir] = true
+	}
+	return scanner.scan(relDir)
+}
+
+type resourceScanner struct {
+	baseDir string
+	rule    resourceRule
+	// visited holds real paths of walked directories to stop symbolic link cycles
+	visited map[string]bool
+}
+
+func (s resourceScanner) scan(relDir string) ([]resourceDecision, error) {
+	decisionList := make([]resourceDecision, 0)
+
+	files, err := ioutil.ReadDir(filepath.Join(s.baseDir, relDir))
+	if err != nil {
+		return decisionList, fmt.Errorf("findResourceFromDirectory error : %w\n", err)
+	}
+
+	for _, file := range files {
+		if file.Name() == farignoreFilename {
+			continue
+		}
+		if file.Name()[0] == '.' && !s.rule.dotfiles {
+			continue
+		}
+
+		relPath := filepath.Join(relDir, file.Name())
+		isDir := file.IsDir()
+		if file.Mode()&os.ModeSymlink != 0 && s.rule.symlinks == SymlinkFollow {
+			isDir = s.enterLinkedDir(relPath)
+		}
+
+		selected, reason := s.rule.decide(relPath, isDir)
+		if isDir && selected {
+			foundList, err := s.scan(relPath)
+			if err != nil {
+				return decisionList, err
+			}
+			decisionList = append(decisionList, foundList...)
+			continue
+		}
+
+		decisionList = append(decisionList, resourceDecision{
+			RelPath:  relPath,
+			IsDir:    isDir,
+			Selected: selected,
+			Rule:     reason,
+		})
+	}
+	return decisionList, nil
+}
+
+// enterLinkedDir reports whether the link at relPath points to a directory not walked yet
+func (s resourceScanner) enterLinkedDir(relPath string) bool {
+	realPath, err := filepath.EvalSymlinks(filepath.Join(s.baseDir, relPath))
+	if err != nil {
+		return false
+	}
+	stat, err := os.Stat(realPath)
+	if err != nil || !stat.IsDir() || s.visited[realPath] {
+		return false
+	}
+	s.visited[realPath] = true
+	return true
+}
+
+// CollisionError lists resource files sharing a name in the far, with each other
+// or with a file gofar writes itself
+type CollisionError struct {
+	// Collisions maps a far path to the project relative paths of its sources.
+	// a file gofar writes is listed as "(binary)" or "(generated)"
+	Collisions map[string][]string
+}
+
+func (e *CollisionError) Error() string {
+	nameList := make([]string, 0, len(e.Collisions))
+	for name := range e.Collisions {
+		nameList = append(nameList, name)
+	}
+	sort.Strings(nameList)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d resource names collide in the far. use preserve_paths or exclude rules", len(nameList)))
+	for _, name := range nameList {
+		sb.WriteString(fmt.Sprintf("\n - %s : %s", name, strings.Join(e.Collisions[name], ", ")))
+	}
+	return sb.String()
+}
+
+// reservedFarNames maps names gofar writes at the far root to their origin
+func (b *Builder) reservedFarNames() map[string]string {
+	reserved := map[string]string{
+		DeploymentFilename:  "(generated)",
+		ManifestFilename:    "(generated)",
+		b.ExposeProcessName: "(binary)",
+	}
+	for _, v := range b.ProcessList {
+		reserved[v.GetBinaryname()] = "(binary)"
+	}
+	return reserved
+}
+
+// checkResourceCollision fails when selected files would overwrite each other or a file
+// gofar writes. flattened files land at the far root by their base name
+func checkResourceCollision(decisionList []resourceDecision, flatten bool, reserved map[string]string) error {
+	sourceMap := make(map[string][]string)
+	for _, v := range decisionList {
+		if !v.Selected || v.IsDir {
+			continue
+		}
+		name := filepath.ToSlash(v.RelPath)
+		if flatten {
+			name = filepath.Base(v.RelPath)
+		}
+		sourceMap[name] = append(sourceMap[name], filepath.ToSlash(v.RelPath))
+	}
+
+	collisions := make(map[string][]string)
+	for name, sourceList := range sourceMap {
+		if origin, ok := reserved[name]; ok {
+			collisions[name] = append(sourceList, origin)
+		} else if len(sourceList) > 1 {
+			collisions[name] = sourceList
+		}
+	}
+	if len(collisions) > 0 {
+		return &CollisionError{Collisions: collisions}
+	}
+	return nil
+}
+
+// isScriptFile reports whether path is a script packaged as executable
+func isScriptFile(path string) bool {
+	return strings.HasSuffix(path, ".sh") ||
+		strings.HasSuffix(path, ".rb") ||
+		strings.HasSuffix(path, ".lua")
+}
@@ -0,0 +1,147 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 9:00
+ */
+
+package far
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func selectedPaths(decisionList []resourceDecision) []string {
+	list := make([]string, 0)
+	for _, v := range decisionList {
+		if v.Selected && !v.IsDir {
+			list = append(list, filepath.ToSlash(v.RelPath))
+		}
+	}
+	return list
+}
+
+func TestFarIgnore(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, farignoreFilename), "# fixtures\ntestdata/\ndocker-compose.yml\n*.json\n!conf/*.json\n")
+	writeTestFile(t, filepath.Join(root, "conf", "app.yaml"), "a: 1")
+	writeTestFile(t, filepath.Join(root, "conf", "schema.json"), "{}")
+	writeTestFile(t, filepath.Join(root, "pkg", "store", "testdata", "fixture.yaml"), "a: 1")
+	writeTestFile(t, filepath.Join(root, "pkg", "store", "sample.json"), "{}")
+	writeTestFile(t, filepath.Join(root, "docker-compose.yml"), "version: 3")
+
+	b := &Builder{ProjectBaseDir: root}
+	rule, err := b.newResourceRule(root, true)
+	assert.Nil(t, err)
+
+	decisionList, err := scanResources(root, "", rule)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"conf/app.yaml", "conf/schema.json"}, selectedPaths(decisionList))
+
+	reasons := make(map[string]string)
+	for _, v := range decisionList {
+		reasons[filepath.ToSlash(v.RelPath)] = v.Rule
+	}
+	assert.Equal(t, ".farignore:2 testdata/", reasons["pkg/store/testdata"])
+	assert.Equal(t, ".farignore:4 *.json", reasons["pkg/store/sample.json"])
+	assert.Equal(t, "suffix yaml", reasons["conf/app.yaml"])
+}
+
+func TestFarIgnoreInResourceDir(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, farignoreFilename), "resources/local/\n")
+	writeTestFile(t, filepath.Join(root, "resources", farignoreFilename), "*.bak\n")
+	writeTestFile(t, filepath.Join(root, "resources", "app.yaml"), "a: 1")
+	writeTestFile(t, filepath.Join(root, "resources", "app.yaml.bak"), "a: 0")
+	writeTestFile(t, filepath.Join(root, "resources", "bin", "start"), "#!/bin/sh")
+	writeTestFile(t, filepath.Join(root, "resources", "local", "dev.yaml"), "a: 2")
+
+	resourceDir := filepath.Join(root, "resources")
+	b := &Builder{ProjectBaseDir: root, ResourceDir: resourceDir, ResourceInclude: nil}
+	rule, err := b.newResourceRule(resourceDir, false)
+	assert.Nil(t, err)
+
+	decisionList, err := scanResources(resourceDir, "", rule)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app.yaml", "bin/start"}, selectedPaths(decisionList))
+}
+
+func TestArtifactDirsNotScanned(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "conf", "app.yaml"), "a: 1")
+	writeTestFile(t, filepath.Join(root, "dist", IndexFilename), "{}")
+	writeTestFile(t, filepath.Join(root, "repo", "saturn", IndexFilename), "{}")
+
+	b := &Builder{ProjectBaseDir: root, OutputDir: filepath.Join(root, "dist"), OutputRoot: filepath.Join(root, "repo")}
+	rule, err := b.newResourceRule(root, true)
+	assert.Nil(t, err)
+
+	decisionList, err := scanResources(root, "", rule)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"conf/app.yaml"}, selectedPaths(decisionList))
+}
+
+func TestFlattenCollision(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "svc", "a", "config.yaml"), "a: 1")
+	writeTestFile(t, filepath.Join(root, "svc", "b", "config.yaml"), "a: 2")
+	writeTestFile(t, filepath.Join(root, "app.properties"), "a=1")
+
+	b := &Builder{ProjectBaseDir: root, workingDir: t.TempDir(), out: io.Discard}
+	err := b.loadResourceFromProject()
+	var collisionErr *CollisionError
+	assert.True(t, errors.As(err, &collisionErr))
+	assert.Equal(t, map[string][]string{"config.yaml": {"svc/a/config.yaml", "svc/b/config.yaml"}}, collisionErr.Collisions)
+
+	b.PreserveResourcePaths = true
+	assert.Nil(t, b.loadResourceFromProject())
+	assert.Nil(t, CheckFileExist(filepath.Join(b.workingDir, "svc", "b", "config.yaml")))
+	assert.Nil(t, CheckFileExist(filepath.Join(b.workingDir, "app.properties")))
+}
+
+func TestGeneratedFileCollision(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "fixtures", DeploymentFilename), "{}")
+	writeTestFile(t, filepath.Join(root, "app.properties"), "a=1")
+
+	b := &Builder{ProjectBaseDir: root, ExposeProcessName: "saturn", workingDir: t.TempDir(), out: io.Discard}
+	err := b.loadResourceFromProject()
+	var collisionErr *CollisionError
+	assert.True(t, errors.As(err, &collisionErr))
+	assert.Equal(t, map[string][]string{DeploymentFilename: {"fixtures/deployment.json", "(generated)"}}, collisionErr.Collisions)
+
+	// kept under fixtures/ it does not collide
+	b.PreserveResourcePaths = true
+	assert.Nil(t, b.loadResourceFromProject())
+
+	// a resource named like a compiled binary
+	resourceDir := filepath.Join(root, "resources")
+	writeTestFile(t, filepath.Join(resourceDir, "saturn-cli.sh"), "#!/bin/sh")
+	writeTestFile(t, filepath.Join(resourceDir, "saturn-cli"), "#!/bin/sh")
+	b.ResourceDir = resourceDir
+	b.ProcessList = []CmdRecord{{Path: filepath.Join(root, "cmd", "saturn")}, {Path: filepath.Join(root, "cmd", "saturn-cli")}}
+	err = b.loadResourceFromDesginatedDir()
+	assert.True(t, errors.As(err, &collisionErr))
+	assert.Equal(t, map[string][]string{"saturn-cli": {"saturn-cli", "(binary)"}}, collisionErr.Collisions)
+}
@@ -0,0 +1,170 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 11:20
+ */
+
+package far
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const signatureSuffix = ".sig"
+
+// ErrSignature reports a far whose signature does not match the public key
+var ErrSignature = errors.New("signature verification failed")
+
+// GenerateKeyPair writes a new ed25519 private key (PKCS#8 PEM, 0600) and public key
+// (PKIX PEM) to the given paths. existing files are never overwritten
+func GenerateKeyPair(privatePath, publicPath string) error {
+	for _, path := range []string{privatePath, publicPath} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("fail to generate key : %w", err)
+	}
+
+	privateDer, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	publicDer, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	err = writePem(privatePath, "PRIVATE KEY", privateDer, 0600)
+	if err != nil {
+		return err
+	}
+	return writePem(publicPath, "PUBLIC KEY", publicDer, 0644)
+}
+
+func writePem(path, blockType string, der []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return pem.Encode(file, &pem.Block{Type: blockType, Bytes: der})
+}
+
+func readPem(path, blockType string) ([]byte, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(dat)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s is not a PEM encoded %s", path, strings.ToLower(blockType))
+	}
+	return block.Bytes, nil
+}
+
+// ReadPrivateKey reads an ed25519 private key written by GenerateKeyPair
+func ReadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPem(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse %s : %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 key", path)
+	}
+	return privateKey, nil
+}
+
+// ReadPublicKey reads an ed25519 public key written by GenerateKeyPair
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPem(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse %s : %w", path, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 key", path)
+	}
+	return publicKey, nil
+}
+
+// SignaturePath returns the detached signature file of farPath
+func SignaturePath(farPath string) string {
+	return farPath + signatureSuffix
+}
+
+// SignFar signs the whole far and writes the base64 encoded signature to SignaturePath(farPath).
+// the far contains manifest.json, so the signature covers every file digest too
+func SignFar(farPath string, key ed25519.PrivateKey) (string, error) {
+	dat, err := os.ReadFile(farPath)
+	if err != nil {
+		return "", err
+	}
+
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, dat))
+	sigPath := SignaturePath(farPath)
+	err = os.WriteFile(sigPath, []byte(signature+"\n"), 0644)
+	if err != nil {
+		return "", fmt.Errorf("fail to write signature : %w", err)
+	}
+	return sigPath, nil
+}
+
+// VerifySignature checks SignaturePath(farPath) against the far with key
+func VerifySignature(farPath string, key ed25519.PublicKey) error {
+	sigPath := SignaturePath(farPath)
+	encoded, err := os.ReadFile(sigPath)
+	if err != nil {
+		return fmt.Errorf("%w : %s", ErrSignature, err.Error())
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("%w : invalid signature file %s", ErrSignature, sigPath)
+	}
+
+	dat, err := os.ReadFile(farPath)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, dat, signature) {
+		return fmt.Errorf("%w : %s", ErrSignature, farPath)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 11:40
+ */
+
+package far
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignFar(t *testing.T) {
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "gofar.key")
+	publicPath := filepath.Join(dir, "gofar.pub")
+	assert.Nil(t, GenerateKeyPair(privatePath, publicPath))
+	assert.NotNil(t, GenerateKeyPair(privatePath, publicPath), "existing key must not be overwritten")
+
+	stat, err := os.Stat(privatePath)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	privateKey, err := ReadPrivateKey(privatePath)
+	assert.Nil(t, err)
+	publicKey, err := ReadPublicKey(publicPath)
+	assert.Nil(t, err)
+	_, err = ReadPublicKey(privatePath)
+	assert.NotNil(t, err)
+
+	farPath := filepath.Join(dir, "saturn.far")
+	writeTestFile(t, farPath, "far content")
+	sigPath, err := SignFar(farPath, privateKey)
+	assert.Nil(t, err)
+	assert.Equal(t, SignaturePath(farPath), sigPath)
+	assert.Nil(t, VerifySignature(farPath, publicKey))
+
+	other := filepath.Join(dir, "other")
+	assert.Nil(t, GenerateKeyPair(other+".key", other+".pub"))
+	otherKey, err := ReadPublicKey(other + ".pub")
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(VerifySignature(farPath, otherKey), ErrSignature))
+
+	writeTestFile(t, farPath, "tampered content")
+	assert.True(t, errors.Is(VerifySignature(farPath, publicKey), ErrSignature))
+
+	assert.Nil(t, os.Remove(sigPath))
+	assert.True(t, errors.Is(VerifySignature(farPath, publicKey), ErrSignature))
+}
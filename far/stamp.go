@@ -0,0 +1,188 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 8:00
+ */
+
+package far
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStampPackage receives build information when no package is configured.
+// declare `var Version, Commit, Branch, BuildTime, BuildUser string` in it
+const defaultStampPackage = "main"
+
+// buildInfo is collected once per Build and shared by binaries and deployment.json
+type buildInfo struct {
+	Time time.Time
+	User string
+	Git  GitInfo
+}
+
+func (i buildInfo) formatTime() string {
+	zoneName, _ := i.Time.Zone()
+	return i.Time.Format(yyyyMMddHHmmss) + " " + zoneName
+}
+
+func (b *Builder) collectBuildInfo() error {
+	info := buildInfo{Time: time.Now()}
+	// find author. a reproducible build does not depend on the account running it
+	info.User = os.Getenv(buildUserEnv)
+	if len(info.User) == 0 && !b.Reproducible {
+		info.User = currentUsername()
+	}
+
+	var err error
+	info.Git, err = readGitInfo(b.ProjectBaseDir, b.OutputDir, b.getOutputRoot())
+	if err != nil {
+		// a clean tree can not be proven without git
+		if b.RequireClean {
+			return fmt.Errorf("clean tree is required : %w", err)
+		}
+		fmt.Fprintf(b.out, "%s\n", err.Error())
+	}
+
+	// version of the nearest tag when none is configured
+	if len(b.Version) == 0 {
+		b.Version = info.Git.Describe()
+	}
+
+	if b.Reproducible {
+		info.Time, err = reproducibleTime(info.Git)
+		if err != nil {
+			return err
+		}
+	}
+	b.buildInfo = info
+	return nil
+}
+
+// maxListedDirtyFiles bounds files listed by checkCleanTree
+const maxListedDirtyFiles = 10
+
+// checkCleanTree fails when the project is not a git worktree or has uncommitted changes.
+// the output directories of gofar are left out of the status by collectBuildInfo
+func checkCleanTree(gitInfo GitInfo) error {
+	if !gitInfo.Valid || !gitInfo.StatusKnown {
+		return fmt.Errorf("clean tree is required but git worktree status is not available")
+	}
+	if !gitInfo.Dirty {
+		return nil
+	}
+
+	fileList := gitInfo.DirtyFiles()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("clean tree is required but %d files are not committed", len(fileList)))
+	for i, v := range fileList {
+		if i == maxListedDirtyFiles {
+			sb.WriteString(fmt.Sprintf("\n - ... %d more", len(fileList)-i))
+			break
+		}
+		sb.WriteString("\n - " + v)
+	}
+	return fmt.Errorf("%s", sb.String())
+}
+
+// reproducibleTime returns SOURCE_DATE_EPOCH, or the commit time of HEAD when it is not set
+func reproducibleTime(gitInfo GitInfo) (time.Time, error) {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); len(epoch) > 0 {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q : %w", epoch, err)
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	if gitInfo.Valid && !gitInfo.CommitTime.IsZero() {
+		return gitInfo.CommitTime.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("reproducible build requires SOURCE_DATE_EPOCH or a git commit")
+}
+
+// buildUserEnv names the build user instead of the current account, which a reproducible build omits
+const buildUserEnv = "GOFAR_BUILD_USER"
+
+func currentUsername() string {
+	if current, err := user.Current(); err == nil && len(current.Username) > 0 {
+		return current.Username
+	}
+	if name := os.Getenv("USER"); len(name) > 0 {
+		return name
+	}
+	return "unknown"
+}
+
+func (b Builder) getStampPackage() string {
+	if len(b.StampPackage) > 0 {
+		return b.StampPackage
+	}
+	return defaultStampPackage
+}
+
+// stampVariables returns -X assignments injected into every binary
+func (b Builder) stampVariables() []string {
+	pkg := b.getStampPackage()
+	values := []struct {
+		name  string
+		value string
+	}{
+		{"Version", b.Version},
+		{"Commit", b.buildInfo.Git.CommitHash},
+		{"Branch", b.buildInfo.Git.BranchName},
+		{"BuildTime", b.buildInfo.formatTime()},
+		{"BuildUser", b.buildInfo.User},
+	}
+
+	list := make([]string, 0, len(values))
+	for _, v := range values {
+		if len(v.value) == 0 {
+			continue
+		}
+		list = append(list, fmt.Sprintf("%s.%s=%s", pkg, v.name, v.value))
+	}
+	return list
+}
+
+// ldflags returns the -ldflags value. assignments containing spaces or quotes
+// are quoted the way the go tool splits flag values
+func (b Builder) ldflags() string {
+	flagList := make([]string, 0)
+	if b.Reproducible {
+		flagList = append(flagList, "-buildid=")
+	}
+	if len(b.BuildCGOLink) > 0 {
+		flagList = append(flagList, "-s", "-w")
+	}
+	for _, v := range b.stampVariables() {
+		if strings.Contains(v, "\"") {
+			v = "'" + v + "'"
+		} else if strings.ContainsAny(v, " \t\n'") {
+			v = "\"" + v + "\""
+		}
+		flagList = append(flagList, "-X", v)
+	}
+	return strings.Join(flagList, " ")
+}
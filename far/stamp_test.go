@@ -0,0 +1,105 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 8:20
+ */
+
+package far
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLdflags(t *testing.T) {
+	b := Builder{Version: "1.4.2", StampPackage: "example.com/saturn/buildinfo"}
+	b.buildInfo = buildInfo{
+		Time: time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC),
+		User: "fatima",
+		Git:  GitInfo{Valid: true, BranchName: "main", CommitHash: "abcdef012345"},
+	}
+
+	assert.Equal(t, "-X example.com/saturn/buildinfo.Version=1.4.2"+
+		" -X example.com/saturn/buildinfo.Commit=abcdef012345"+
+		" -X example.com/saturn/buildinfo.Branch=main"+
+		` -X "example.com/saturn/buildinfo.BuildTime=2026-10-17 20:00:00 UTC"`+
+		" -X example.com/saturn/buildinfo.BuildUser=fatima", b.ldflags())
+
+	b.BuildCGOLink = "x86_64-pc-linux-gcc"
+	b.Version = ""
+	b.StampPackage = ""
+	assert.Equal(t, "-s -w -X main.Commit=abcdef012345 -X main.Branch=main"+
+		` -X "main.BuildTime=2026-10-17 20:00:00 UTC" -X main.BuildUser=fatima`, b.ldflags())
+}
+
+func TestReproducibleTime(t *testing.T) {
+	commitTime := time.Date(2026, 10, 1, 9, 30, 0, 0, time.FixedZone("KST", 9*3600))
+	gitInfo := GitInfo{Valid: true, CommitTime: commitTime}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	buildTime, err := reproducibleTime(gitInfo)
+	assert.Nil(t, err)
+	assert.Equal(t, commitTime.UTC(), buildTime)
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	buildTime, err = reproducibleTime(gitInfo)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), buildTime)
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	_, err = reproducibleTime(gitInfo)
+	assert.NotNil(t, err)
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	_, err = reproducibleTime(GitInfo{})
+	assert.NotNil(t, err)
+}
+
+func TestReproducibleBuildIgnoresUser(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles a project")
+	}
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "go.mod"), "module example.com/saturn\n\ngo 1.18\n")
+	writeTestFile(t, filepath.Join(root, "cmd", "saturn", "main.go"), "package main\n\nvar BuildUser string\n\nfunc main() { println(BuildUser) }\n")
+	writeTestFile(t, filepath.Join(root, "conf", "app.yaml"), "name: saturn\n")
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	t.Setenv(buildUserEnv, "")
+
+	on := true
+	build := func(user string) *Artifact {
+		t.Setenv("USER", user)
+		t.Setenv("LOGNAME", user)
+		b, err := NewBuilder(Options{WorkDir: root, ProcessName: "saturn", OutputDir: t.TempDir(), Reproducible: &on})
+		assert.Nil(t, err)
+		artifactList, err := b.Build()
+		assert.Nil(t, err)
+		assert.Len(t, artifactList, 1)
+		return artifactList[0]
+	}
+
+	alice := build("alice")
+	bob := build("bob")
+	assert.Equal(t, "", alice.Deployment.Build.User)
+	assert.Equal(t, alice.SHA256, bob.SHA256)
+}
@@ -23,11 +23,10 @@
  * //
  */
 
-package main
+package far
 
 import (
 	"archive/zip"
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -35,8 +34,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strings"
+	"sort"
+	"time"
 )
 
 func CheckDirExist(path string) error {
@@ -55,26 +54,25 @@ func CheckDirExist(path string) error {
 	return nil
 }
 
-func EnsureFileInDirectory(dir, targetFilename string) bool {
+// EnsureFileInDirectory reports whether dir contains the regular file targetFilename
+func EnsureFileInDirectory(dir, targetFilename string) (bool, error) {
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "fail to read dir %s : %s", dir, err.Error())
-		return false
+		return false, fmt.Errorf("fail to read dir %s : %w", dir, err)
 	}
 
 	// find .git
 	for _, file := range files {
 		if file.Name() == targetFilename {
 			if !file.IsDir() {
-				return true
+				return true, nil
 			}
-			fmt.Fprintf(os.Stderr, "%s found but it is directory", dir)
-			return false
+			return false, fmt.Errorf("%s found in %s but it is directory", targetFilename, dir)
 		}
 	}
 
 	// not found
-	return false
+	return false, nil
 }
 
 var errGopathNotFound = fmt.Errorf("not found GOPATH. (gopath is nil)")
@@ -82,11 +80,7 @@ var errGitNotFound = fmt.Errorf("not found git")
 
 func FindGitConfig(dir string) (string, error) {
 	gopath := os.Getenv("GOPATH")
-	if len(gopath) == 0 {
-		return "", errGopathNotFound
-	}
-
-	if dir == filepath.Join(gopath, "src") {
+	if len(gopath) > 0 && dir == filepath.Join(gopath, "src") {
 		return "", errGitNotFound
 	}
 
@@ -98,13 +92,23 @@ func FindGitConfig(dir string) (string, error) {
 	// find .git
 	for _, file := range files {
 		if file.Name() == gitDirname {
-			if file.IsDir() && EnsureFileInDirectory(filepath.Join(dir, file.Name()), gitConfigfile) {
+			if !file.IsDir() {
+				continue
+			}
+			found, err := EnsureFileInDirectory(filepath.Join(dir, file.Name()), gitConfigfile)
+			if err != nil {
+				return "", err
+			}
+			if found {
 				return dir, nil
 			}
 		}
 	}
 
 	parentDir := filepath.Dir(dir)
+	if parentDir == dir {
+		return "", errGitNotFound
+	}
 	return FindGitConfig(parentDir)
 }
 
@@ -140,12 +144,11 @@ func FindDirectory(baseDir, targetDir string) (string, error) {
 	return "", fmt.Errorf("%s not found", targetDir)
 }
 
-func FindSubDirectories(baseDir string) []string {
+func FindSubDirectories(baseDir string) ([]string, error) {
 	list := make([]string, 0)
 	files, err := ioutil.ReadDir(baseDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "fail to read dir : %s", err.Error())
-		return list
+		return list, fmt.Errorf("fail to read dir : %w", err)
 	}
 
 	for _, file := range files {
@@ -155,7 +158,7 @@ func FindSubDirectories(baseDir string) []string {
 		}
 	}
 
-	return list
+	return list, nil
 }
 
 func getFileModtime(path string) string {
@@ -177,7 +180,6 @@ func CheckFileExist(path string) error {
 }
 
 func CopyFile(src string, dst string) error {
-	fmt.Printf("copy : %s\n", src)
 	sFile, err := os.Open(src)
 	if err != nil {
 		return err
@@ -203,106 +205,65 @@ func CopyFile(src string, dst string) error {
 	return nil
 }
 
-func ExecuteCommand(wd, command string) (string, error) {
+// ExecuteShellStream runs command with /bin/sh and writes combined output to w as it is produced.
+// env is appended to the environment of gofar. use it only for user declared scripts
+func ExecuteShellStream(wd, command string, env []string, w io.Writer) error {
 	if len(command) == 0 {
-		return "", errors.New("empty command")
-	}
-
-	var cmd *exec.Cmd
-	s := regexp.MustCompile("\\s+").Split(command, -1)
-	i := len(s)
-	if i == 0 {
-		return "", errors.New("empty command")
-	} else if i == 1 {
-		cmd = exec.Command(s[0])
-	} else {
-		cmd = exec.Command(s[0], s[1:]...)
+		return errors.New("empty command")
 	}
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = w
+	cmd.Stderr = w
 	cmd.Dir = wd
-	err := cmd.Run()
-	if err != nil {
-		return out.String(), err
-	}
-	return out.String(), nil
+	return cmd.Run()
 }
 
-func ExecuteShell(wd, command string) (string, error) {
-	if len(command) == 0 {
-		return "", errors.New("empty command")
-	}
-
-	var cmd *exec.Cmd
-	cmd = exec.Command("/bin/sh", "-c", command)
-	//s := regexp.MustCompile("\\s+").Split(command, -1)
-	//i := len(s)
-	//if i == 0 {
-	//	return "", errors.New("empty command")
-	//} else if i == 1 {
-	//	cmd = exec.Command(s[0])
-	//} else {
-	//	cmd = exec.Command(s[0], s[1:]...)
-	//}
-
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &out
-	cmd.Dir = wd
-	err := cmd.Run()
-	if err != nil {
-		return out.String(), err
-	}
-	return out.String(), nil
+// Zipit compresses the files under source into target
+func Zipit(source, target string) error {
+	return zipDir(source, target, nil)
 }
 
-func ReadGitBranch(baseDir string) (string, error) {
-	headFile := filepath.Join(baseDir, ".git", "HEAD")
-
-	dat, err := os.ReadFile(headFile)
-	if err != nil {
-		return "", fmt.Errorf("not found git head")
-	}
-
-	head := strings.Trim(string(dat), " \r\n\t")
-	idx := strings.LastIndex(head, "/")
-	return head[idx+1:], nil
+// ZipitReproducible compresses like Zipit but entries are sorted by name, stamped with modTime
+// and get fixed permissions, 0755 for directories and executables and 0644 for others
+func ZipitReproducible(source, target string, modTime time.Time) error {
+	return zipDir(source, target, func(header *zip.FileHeader) {
+		header.Modified = modTime.UTC()
+		header.SetMode(normalizeMode(header.Mode()))
+	})
 }
 
-func ReadGitCommit(baseDir string, branch string) string {
-	commitFile := filepath.Join(baseDir, ".git", "refs", "heads", branch)
-	dat, err := os.ReadFile(commitFile)
-	if err != nil {
-		return ""
+// normalizeMode returns the fixed permission of a reproducible far entry
+func normalizeMode(mode os.FileMode) os.FileMode {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return os.ModeSymlink | 0777
+	case mode.IsDir():
+		return os.ModeDir | 0755
+	case mode&0111 != 0:
+		return 0755
+	default:
+		return 0644
 	}
-
-	return string(dat)[:12]
 }
 
-func Zipit(source, target string) error {
-	zipfile, err := os.Create(target)
-	if err != nil {
-		return err
-	}
-	defer zipfile.Close()
-
-	archive := zip.NewWriter(zipfile)
-	defer archive.Close()
+type zipEntry struct {
+	path   string
+	info   os.FileInfo
+	header *zip.FileHeader
+}
 
-	_, err = os.Stat(source)
+// zipDir writes the files under source into target in entry name order. normalize, when given,
+// adjusts each header before it is written
+func zipDir(source, target string, normalize func(*zip.FileHeader)) error {
+	_, err := os.Stat(source)
 	if err != nil {
 		return nil
 	}
 
-	var baseDir string
-	//if info.IsDir() {
-	//	baseDir = filepath.Base(source)
-	//}
-
-	filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
-		//fmt.Printf("zip path : %s\n", path)
+	entryList := make([]zipEntry, 0)
+	err = filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
 		if source == path {
 			return nil
 		}
@@ -316,34 +277,73 @@ func Zipit(source, target string) error {
 			return err
 		}
 
-		if baseDir != "" {
-			header.Name = filepath.Join(baseDir, strings.TrimPrefix(path, source))
+		// entry name is the slash separated path relative to source
+		relPath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
 		}
+		header.Name = filepath.ToSlash(relPath)
 
 		if info.IsDir() {
 			header.Name += "/"
 		} else {
 			header.Method = zip.Deflate
 		}
+		if normalize != nil {
+			normalize(header)
+		}
+		entryList = append(entryList, zipEntry{path: path, info: info, header: header})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(entryList, func(i, j int) bool {
+		return entryList[i].header.Name < entryList[j].header.Name
+	})
 
-		writer, err := archive.CreateHeader(header)
-		if err != nil {
+	zipfile, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer zipfile.Close()
+
+	archive := zip.NewWriter(zipfile)
+	for _, entry := range entryList {
+		if err = writeZipEntry(archive, entry); err != nil {
+			archive.Close()
 			return err
 		}
+	}
+	return archive.Close()
+}
 
-		if info.IsDir() {
-			return nil
-		}
+func writeZipEntry(archive *zip.Writer, entry zipEntry) error {
+	writer, err := archive.CreateHeader(entry.header)
+	if err != nil {
+		return err
+	}
 
-		file, err := os.Open(path)
+	if entry.info.IsDir() {
+		return nil
+	}
+
+	// symbolic link is stored with its target as content
+	if entry.info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(entry.path)
 		if err != nil {
 			return err
 		}
-		defer file.Close()
-		_, err = io.Copy(writer, file)
+		_, err = io.WriteString(writer, link)
 		return err
-	})
+	}
 
+	file, err := os.Open(entry.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(writer, file)
 	return err
 }
 
@@ -0,0 +1,92 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with p work for additional information
+ * regarding copyright ownership.  The ASF licenses p file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use p file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ *
+ * @project fatima
+ * @author DeockJin Chung (jin.freestyle@gmail.com)
+ * @date 26. 10. 17. 오후 9:40
+ */
+
+package far
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZipitReproducible(t *testing.T) {
+	modTime := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	build := func(fileTime time.Time, perm os.FileMode) []byte {
+		source := t.TempDir()
+		writeTestFile(t, filepath.Join(source, "saturn"), "binary")
+		writeTestFile(t, filepath.Join(source, "conf", "app.yaml"), "port: 8080\n")
+		writeTestFile(t, filepath.Join(source, "deployment.json"), "{}")
+		assert.Nil(t, os.Chmod(filepath.Join(source, "saturn"), perm))
+		for _, name := range []string{"saturn", "conf/app.yaml", "deployment.json", "conf"} {
+			assert.Nil(t, os.Chtimes(filepath.Join(source, name), fileTime, fileTime))
+		}
+
+		target := filepath.Join(t.TempDir(), "saturn.far")
+		assert.Nil(t, ZipitReproducible(source, target, modTime))
+		dat, err := os.ReadFile(target)
+		assert.Nil(t, err)
+		return dat
+	}
+
+	first := build(time.Now(), 0700)
+	second := build(time.Now().Add(-time.Hour), 0755)
+	assert.Equal(t, first, second)
+
+	target := filepath.Join(t.TempDir(), "saturn.far")
+	assert.Nil(t, os.WriteFile(target, first, 0644))
+	r, err := zip.OpenReader(target)
+	assert.Nil(t, err)
+	defer r.Close()
+
+	nameList := make([]string, 0)
+	for _, f := range r.File {
+		nameList = append(nameList, f.Name)
+		assert.True(t, f.Modified.Equal(modTime), f.Name)
+	}
+	assert.Equal(t, []string{"conf/", "conf/app.yaml", "deployment.json", "saturn"}, nameList)
+	assert.Equal(t, os.FileMode(0755), r.File[3].Mode())
+	assert.Equal(t, os.FileMode(0644), r.File[1].Mode())
+}
+
+func TestDirectoryLookupErrors(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, ".git", "config"), 0755))
+
+	found, err := EnsureFileInDirectory(filepath.Join(root, ".git"), "config")
+	assert.False(t, found)
+	assert.NotNil(t, err, "config is a directory")
+
+	found, err = EnsureFileInDirectory(filepath.Join(root, "missing"), "config")
+	assert.False(t, found)
+	assert.NotNil(t, err)
+
+	_, err = FindSubDirectories(filepath.Join(root, "missing"))
+	assert.NotNil(t, err)
+	dirList, err := FindSubDirectories(root)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(root, ".git")}, dirList)
+}
@@ -23,6 +23,8 @@
 
 package main
 
+import "os"
+
 func main() {
-	Gofar()
+	os.Exit(Gofar())
 }
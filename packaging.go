@@ -29,59 +29,249 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"throosea.com/gofar/far"
 )
 
-var usage = `usage: %s process_name os_arc cgo
-usage: %s version
+var usage = `usage: %[1]s <command> [options] [arguments]
+       %[1]s process_name [os_arc] [cgo]
 
 golang fatima package builder
 
-positional arguments:
+commands:
+  build [process_name]  build binaries and package them into a far
+  inspect far_file      print deployment, files and binaries of a far
+  verify far_file       check integrity and signature of a far
+  keygen [name]         create an ed25519 key pair signing fars
+  diff old_far new_far  compare deployment, files and dependencies of two fars
+  extract far_file      extract a far into a directory
+  publish far_file      upload a far with its checksum and signature to an http repository
+  install far_file      install a far as a new release of an application home
+  rollback process_name switch an installed process back to its previous release
+  list [process_name]   list far artifacts
+  clean [process_name]  remove far artifacts
+  prune [process_name]  remove old far artifacts by retention policy
+  version               print gofar version
+
+legacy positional arguments (same as build):
   process_name          process(program) name
   os_arc                optional. e.g) linux_amd64
   cgo                   CC link e.g) x86_64-pc-linux-gcc
+
+run '%[1]s <command> -h' for options of a command
 `
 
 var version = "1.0.4"
 
-func Gofar() {
-	if len(os.Args) > 1 {
-		if os.Args[1] == "version" {
-			fmt.Printf("gofar version %s\n", version)
-			return
-		}
-	}
+type command struct {
+	name string
+	run  func(args []string) error
+}
 
+var commandList = []command{
+	{"build", runBuild},
+	{"inspect", runInspect},
+	{"verify", runVerify},
+	{"keygen", runKeygen},
+	{"diff", runDiff},
+	{"extract", runExtract},
+	{"publish", runPublish},
+	{"install", runInstall},
+	{"rollback", runRollback},
+	{"list", runList},
+	{"clean", runClean},
+	{"prune", runPrune},
+	{"version", runVersion},
+}
+
+// Gofar runs the command line and returns the process exit code
+func Gofar() int {
 	flag.Usage = func() {
-		fmt.Printf(usage, os.Args[0], os.Args[0])
+		fmt.Printf(usage, filepath.Base(os.Args[0]))
 	}
 
-	flag.Parse()
-	if len(flag.Args()) < 1 {
+	if len(os.Args) < 2 {
 		flag.Usage()
-		return
+		return ExitUsage
+	}
+
+	name := os.Args[1]
+	args := os.Args[2:]
+	run := findCommand(name)
+	if run == nil {
+		if strings.HasPrefix(name, "-") {
+			flag.Usage()
+			return ExitUsage
+		}
+		// legacy : process_name os_arc cgo
+		run = runLegacyBuild
+		args = os.Args[1:]
+	}
+
+	err := run(args)
+	code := ExitCode(err)
+	if code != ExitOK {
+		fmt.Fprintf(os.Stderr, "gofar %s fail : %s\n", name, err.Error())
+	}
+	return code
+}
+
+func findCommand(name string) func(args []string) error {
+	for _, v := range commandList {
+		if v.name == name {
+			return v.run
+		}
+	}
+	return nil
+}
+
+// parseFlags parses flags placed before, between or after positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return nil, err
+			}
+			return nil, &UsageError{Message: err.Error()}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
+}
 
-	osArc := ""
-	processName := flag.Args()[0]
-	if len(flag.Args()) >= 2 {
-		osArc = flag.Args()[1]
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s %s\n\n", filepath.Base(os.Args[0]), name, synopsis)
+		fs.PrintDefaults()
 	}
-	cgoLink := ""
-	if len(flag.Args()) >= 3 {
-		cgoLink = flag.Args()[2]
+	return fs
+}
+
+type buildFlags struct {
+	osArch        string
+	cc            string
+	output        string
+	resourceDir   string
+	jobs          int
+	stampPackage  string
+	verbose       bool
+	preservePaths bool
+	symlinks      string
+	dryRun        bool
+	reproducible  bool
+	signKey       string
+	requireClean  bool
+	version       string
+	outputRoot    string
+	nameTemplate  string
+	// explicit holds names of flags given on the command line
+	explicit map[string]bool
+}
+
+// boolOption returns value when the flag was given on the command line, nil to keep gofar.yaml
+func (f buildFlags) boolOption(name string, value bool) *bool {
+	if !f.explicit[name] {
+		return nil
 	}
+	return &value
+}
 
-	ctx, err := NewBuildContext(processName, osArc, cgoLink)
+func runBuild(args []string) error {
+	fs := newFlagSet("build", "[options] [process_name]")
+	f := buildFlags{}
+	fs.StringVar(&f.osArch, "os-arch", "", "comma separated target platforms e.g) linux_amd64,linux_arm64 or all")
+	fs.StringVar(&f.cc, "cc", "", "CC link for cgo e.g) x86_64-pc-linux-gcc")
+	fs.StringVar(&f.output, "output", "", "directory where the far is written, replacing a far of the same name")
+	fs.StringVar(&f.outputRoot, "output-root", "", "artifact repository keeping every build under <root>/<process>/<build id> (default: $GOPATH/far)")
+	fs.StringVar(&f.nameTemplate, "name-template", "", "far name with {process}, {version}, {platform}, {commit}, {date} (default: {process}-{version})")
+	fs.StringVar(&f.resourceDir, "resource-dir", "", "directory whose files are copied as resources")
+	fs.IntVar(&f.jobs, "j", 0, "number of binaries compiled concurrently (default: number of CPUs)")
+	fs.StringVar(&f.version, "version", "", "version of the far (default: git describe of the nearest tag)")
+	fs.StringVar(&f.stampPackage, "stamp-package", "", "package whose Version, Commit, Branch, BuildTime, BuildUser variables are set (default: main)")
+	fs.BoolVar(&f.verbose, "v", false, "print the rule deciding each resource file")
+	fs.BoolVar(&f.preservePaths, "preserve-paths", false, "keep project relative paths of scanned resources in the far")
+	fs.StringVar(&f.symlinks, "symlinks", "", "symbolic link policy of resources : follow, preserve or reject (default: follow)")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "print the build plan without packaging")
+	fs.StringVar(&f.signKey, "sign-key", "", "ed25519 private key file signing the far (see keygen)")
+	fs.BoolVar(&f.requireClean, "require-clean", false, "refuse to package a project with uncommitted changes")
+	fs.BoolVar(&f.reproducible, "reproducible", false, "normalize timestamps and build paths so identical inputs produce an identical far")
+	positional, err := parseFlags(fs, args)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "packaging error : %s", err.Error())
-		return
+		return err
+	}
+	if len(positional) > 1 {
+		fs.Usage()
+		return &UsageError{Message: "too many arguments"}
 	}
 
-	ctx.Print()
+	f.explicit = make(map[string]bool)
+	fs.Visit(func(fl *flag.Flag) {
+		f.explicit[fl.Name] = true
+	})
+
+	processName := ""
+	if len(positional) == 1 {
+		processName = positional[0]
+	}
+	return buildProcess(processName, f)
+}
 
-	err = ctx.Packaging()
+func runLegacyBuild(args []string) error {
+	f := buildFlags{}
+	processName := args[0]
+	if len(args) >= 2 {
+		f.osArch = args[1]
+	}
+	if len(args) >= 3 {
+		f.cc = args[2]
+	}
+	return buildProcess(processName, f)
+}
+
+func buildProcess(processName string, f buildFlags) error {
+	builder, err := far.NewBuilder(far.Options{
+		ProcessName:           processName,
+		OSArch:                f.osArch,
+		CC:                    f.cc,
+		OutputDir:             f.output,
+		OutputRoot:            f.outputRoot,
+		NameTemplate:          f.nameTemplate,
+		ResourceDir:           f.resourceDir,
+		Jobs:                  f.jobs,
+		Version:               f.version,
+		StampPackage:          f.stampPackage,
+		Verbose:               f.verbose,
+		PreserveResourcePaths: f.boolOption("preserve-paths", f.preservePaths),
+		SymlinkPolicy:         far.SymlinkPolicy(f.symlinks),
+		Reproducible:          f.boolOption("reproducible", f.reproducible),
+		SignKey:               f.signKey,
+		RequireClean:          f.boolOption("require-clean", f.requireClean),
+		Output:                os.Stdout,
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "gofar packaging fail : %s", err.Error())
+		return err
+	}
+
+	builder.Print()
+
+	if f.dryRun {
+		builder.PrintPlan()
+		return nil
 	}
+
+	_, err = builder.Build()
+	return err
+}
+
+func runVersion(args []string) error {
+	fmt.Printf("gofar version %s\n", version)
+	return nil
 }